@@ -0,0 +1,224 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy selects what an AsyncWriter does with an entry when its queue
+// is full.
+type OverflowPolicy struct {
+	mode     overflowMode
+	minLevel Level
+}
+
+type overflowMode int
+
+const (
+	overflowBlock overflowMode = iota
+	overflowDropNewest
+	overflowDropOldest
+	overflowDropBelow
+)
+
+var (
+	// OverflowBlock makes Write wait until there is room in the queue.
+	OverflowBlock = OverflowPolicy{mode: overflowBlock}
+	// OverflowDropNewest discards the entry being written.
+	OverflowDropNewest = OverflowPolicy{mode: overflowDropNewest}
+	// OverflowDropOldest discards the oldest queued entry to make room.
+	OverflowDropOldest = OverflowPolicy{mode: overflowDropOldest}
+)
+
+// OverflowDropBelow discards entries below the given level and makes Write wait
+// for room for all others, so that errors are never lost to a flood of debug
+// output.
+func OverflowDropBelow(level Level) OverflowPolicy {
+	return OverflowPolicy{mode: overflowDropBelow, minLevel: level}
+}
+
+var errAsyncWriterClosed = errors.New("AsyncWriter is closed")
+
+// AsyncWriter is a Writer that queues entries and writes them to another Writer
+// from a background goroutine, so that logging callers are not slowed down by
+// a slow destination.  When the queue is full, the OverflowPolicy decides
+// whether to wait or which entry to drop.
+//
+// Entries are formatted by the underlying Writer after Write returns, so
+// callers must not modify values passed as log arguments afterwards.
+type AsyncWriter struct {
+	writer  Writer
+	policy  OverflowPolicy
+	queue   chan queuedEntry
+	done    chan struct{}
+	dropped uint64
+
+	// mutex guards closed, so that no Write sends on a closed queue.
+	mutex  sync.RWMutex
+	closed bool
+
+	// Each Write is numbered.  pending holds the numbers of entries that have
+	// been queued but not yet written or dropped, and all entries up to and
+	// including finished have been.  flushes are the Flush calls waiting for
+	// finished to reach their number.
+	pendingMutex sync.Mutex
+	seq          uint64
+	pending      map[uint64]struct{}
+	finished     uint64
+	flushes      []asyncFlush
+}
+
+type queuedEntry struct {
+	Entry
+	seq uint64
+}
+
+// asyncFlush is a Flush call waiting for the entries up to seq.
+type asyncFlush struct {
+	seq  uint64
+	done chan struct{}
+}
+
+// NewAsyncWriter starts a background goroutine writing entries to w and returns
+// an AsyncWriter that queues up to size entries for it.  Call Close to drain the
+// queue and stop the goroutine.
+func NewAsyncWriter(w Writer, size int, policy OverflowPolicy) *AsyncWriter {
+	a := &AsyncWriter{
+		writer:  w,
+		policy:  policy,
+		queue:   make(chan queuedEntry, size),
+		done:    make(chan struct{}),
+		pending: map[uint64]struct{}{},
+	}
+	go a.run()
+	return a
+}
+
+func (a *AsyncWriter) run() {
+	for q := range a.queue {
+		if err := a.writer.Write(q.Entry); err != nil {
+			fmt.Fprintf(os.Stderr, "Log write failed: %v\nEntry: %#v", err, q.Entry)
+		}
+		a.removePending(q.seq)
+	}
+	close(a.done)
+}
+
+// Write queues e for writing.  It only returns an error if the writer has been
+// closed; errors from the underlying Writer are reported on stderr.
+func (a *AsyncWriter) Write(e Entry) error {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	if a.closed {
+		return errAsyncWriterClosed
+	}
+
+	q := queuedEntry{e, a.addPending()}
+	switch {
+	case a.policy.mode == overflowBlock:
+		a.queue <- q
+	case a.policy.mode == overflowDropBelow && e.Level >= a.policy.minLevel:
+		a.queue <- q
+	case a.policy.mode == overflowDropOldest:
+		for {
+			select {
+			case a.queue <- q:
+				return nil
+			default:
+			}
+			select {
+			case old := <-a.queue:
+				a.drop(old.seq)
+			default:
+			}
+		}
+	default:
+		select {
+		case a.queue <- q:
+		default:
+			a.drop(q.seq)
+		}
+	}
+	return nil
+}
+
+// Dropped returns the number of entries discarded because the queue was full.
+func (a *AsyncWriter) Dropped() uint64 { return atomic.LoadUint64(&a.dropped) }
+
+// Flush waits until all entries queued so far have been written or dropped, or
+// until ctx is done.  Entries written after Flush is called are not waited for,
+// so Flush returns even while other goroutines keep logging.
+func (a *AsyncWriter) Flush(ctx context.Context) error {
+	a.pendingMutex.Lock()
+	if a.finished >= a.seq {
+		a.pendingMutex.Unlock()
+		return nil
+	}
+	f := asyncFlush{a.seq, make(chan struct{})}
+	a.flushes = append(a.flushes, f)
+	a.pendingMutex.Unlock()
+
+	select {
+	case <-f.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close writes all queued entries and stops the background goroutine.  Writes
+// after Close fail.
+func (a *AsyncWriter) Close() error {
+	a.mutex.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.queue)
+	}
+	a.mutex.Unlock()
+	<-a.done
+	return nil
+}
+
+func (a *AsyncWriter) drop(seq uint64) {
+	atomic.AddUint64(&a.dropped, 1)
+	a.removePending(seq)
+}
+
+// addPending numbers a new entry.
+func (a *AsyncWriter) addPending() uint64 {
+	a.pendingMutex.Lock()
+	defer a.pendingMutex.Unlock()
+	a.seq++
+	a.pending[a.seq] = struct{}{}
+	return a.seq
+}
+
+// removePending records that entry seq has been written or dropped, and
+// releases the Flush calls waiting for it.
+func (a *AsyncWriter) removePending(seq uint64) {
+	a.pendingMutex.Lock()
+	defer a.pendingMutex.Unlock()
+	delete(a.pending, seq)
+	if seq != a.finished+1 {
+		return
+	}
+	for a.finished < a.seq {
+		if _, ok := a.pending[a.finished+1]; ok {
+			break
+		}
+		a.finished++
+	}
+	waiting := a.flushes[:0]
+	for _, f := range a.flushes {
+		if f.seq <= a.finished {
+			close(f.done)
+		} else {
+			waiting = append(waiting, f)
+		}
+	}
+	a.flushes = waiting
+}
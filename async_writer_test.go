@@ -0,0 +1,171 @@
+package logging
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// gatedWriter records entries, but blocks each write until released.
+type gatedWriter struct {
+	started chan struct{}
+	release chan struct{}
+	mutex   sync.Mutex
+	msgs    []string
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{started: make(chan struct{}, 100), release: make(chan struct{})}
+}
+
+func (g *gatedWriter) Write(e Entry) error {
+	g.started <- struct{}{}
+	<-g.release
+	g.mutex.Lock()
+	g.msgs = append(g.msgs, e.Message())
+	g.mutex.Unlock()
+	return nil
+}
+
+func (g *gatedWriter) Msgs() []string {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return append([]string(nil), g.msgs...)
+}
+
+func TestAsyncWriter(t *testing.T) {
+	Convey("AsyncWriter", t, func() {
+		g := newGatedWriter()
+		entry := func(level Level, msg string) Entry { return Entry{Level: level, Fmt: msg} }
+
+		// fill blocks the background goroutine on the first entry and then
+		// fills the queue of two entries.
+		fill := func(a *AsyncWriter) {
+			a.Write(entry(InfoLevel, "0"))
+			<-g.started
+			a.Write(entry(InfoLevel, "1"))
+			a.Write(entry(InfoLevel, "2"))
+		}
+		finish := func(a *AsyncWriter) {
+			close(g.release)
+			So(a.Flush(context.Background()), ShouldBeNil)
+			So(a.Close(), ShouldBeNil)
+		}
+
+		Convey("should write entries in order", func() {
+			a := NewAsyncWriter(g, 10, OverflowBlock)
+			close(g.release)
+			for _, msg := range []string{"a", "b", "c"} {
+				a.Write(entry(InfoLevel, msg))
+			}
+			So(a.Flush(context.Background()), ShouldBeNil)
+			So(g.Msgs(), ShouldResemble, []string{"a", "b", "c"})
+			So(a.Close(), ShouldBeNil)
+		})
+		Convey("should drop the newest entry when full", func() {
+			a := NewAsyncWriter(g, 2, OverflowDropNewest)
+			fill(a)
+			a.Write(entry(ErrorLevel, "3"))
+			finish(a)
+			So(g.Msgs(), ShouldResemble, []string{"0", "1", "2"})
+			So(a.Dropped(), ShouldEqual, 1)
+		})
+		Convey("should drop the oldest entry when full", func() {
+			a := NewAsyncWriter(g, 2, OverflowDropOldest)
+			fill(a)
+			a.Write(entry(InfoLevel, "3"))
+			finish(a)
+			So(g.Msgs(), ShouldResemble, []string{"0", "2", "3"})
+			So(a.Dropped(), ShouldEqual, 1)
+		})
+		Convey("should drop only low-level entries when full", func() {
+			a := NewAsyncWriter(g, 2, OverflowDropBelow(ErrorLevel))
+			fill(a)
+			a.Write(entry(InfoLevel, "dropped"))
+			written := make(chan struct{})
+			go func() {
+				a.Write(entry(ErrorLevel, "kept"))
+				close(written)
+			}()
+			select {
+			case <-written:
+				t.Error("Write should block until there is room")
+			case <-time.After(10 * time.Millisecond):
+			}
+			finish(a)
+			<-written
+			So(g.Msgs(), ShouldResemble, []string{"0", "1", "2", "kept"})
+			So(a.Dropped(), ShouldEqual, 1)
+		})
+		Convey("should give up flushing when the context is done", func() {
+			a := NewAsyncWriter(g, 2, OverflowBlock)
+			fill(a)
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+			defer cancel()
+			So(a.Flush(ctx), ShouldResemble, context.DeadlineExceeded)
+			finish(a)
+		})
+		Convey("should flush while other goroutines keep logging", func() {
+			a := NewAsyncWriter(g, 2, OverflowBlock)
+			// Write slowly, so that the queue never empties.
+			closed := make(chan struct{})
+			go func() {
+				for {
+					select {
+					case <-g.started:
+						time.Sleep(100 * time.Microsecond)
+						g.release <- struct{}{}
+					case <-closed:
+						return
+					}
+				}
+			}()
+			a.Write(entry(InfoLevel, "before"))
+			stop := make(chan struct{})
+			var logging sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				logging.Add(1)
+				go func() {
+					defer logging.Done()
+					for {
+						select {
+						case <-stop:
+							return
+						default:
+							a.Write(entry(InfoLevel, "during"))
+						}
+					}
+				}()
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			So(a.Flush(ctx), ShouldBeNil)
+			So(g.Msgs()[0], ShouldEqual, "before")
+			close(stop)
+			logging.Wait()
+			So(a.Close(), ShouldBeNil)
+			close(closed)
+		})
+		Convey("should drain on Close and reject later writes", func() {
+			a := NewAsyncWriter(g, 10, OverflowBlock)
+			close(g.release)
+			a.Write(entry(InfoLevel, "a"))
+			a.Write(entry(InfoLevel, "b"))
+			So(a.Close(), ShouldBeNil)
+			So(g.Msgs(), ShouldResemble, []string{"a", "b"})
+			So(a.Write(entry(InfoLevel, "c")), ShouldNotBeNil)
+			So(a.Close(), ShouldBeNil)
+		})
+		Convey("should serve as the Writer of a StdLogger", func() {
+			a := NewAsyncWriter(g, 10, OverflowBlock)
+			close(g.release)
+			log := &StdLogger{Context: "ctx", Writer: a, MinLevel: TraceLevel}
+			log.Infof("Creating %d blocks", 20)
+			So(a.Close(), ShouldBeNil)
+			So(g.Msgs(), ShouldResemble, []string{"Creating 20 blocks"})
+		})
+	})
+}
@@ -0,0 +1,154 @@
+package logging
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Helper marks the calling function as a logging helper, like testing.T.Helper.
+// Entries logged from within a helper, directly or through other helpers,
+// report the helper's caller as their origin.  Helper is cheap to call
+// repeatedly.
+//
+// Functions in this package are always skipped when looking for the origin of
+// an entry, so Loggers that wrap other Loggers need not call Helper.  So are
+// those of log and log/slog, whose output CaptureStdLog and SlogHandler pass
+// on.  To skip a fixed number of frames instead, use WithCallerSkip.
+func Helper() {
+	var pc [1]uintptr
+	if runtime.Callers(2, pc[:]) == 0 { // skip runtime.Callers and Helper
+		return
+	}
+	helpers.RLock()
+	known := helpers.pcs[pc[0]]
+	helpers.RUnlock()
+	if known {
+		return
+	}
+
+	frame, _ := runtime.CallersFrames(pc[:]).Next()
+	helpers.Lock()
+	helpers.pcs[pc[0]] = true
+	if !helpers.funcs[frame.Function] {
+		helpers.funcs[frame.Function] = true
+		// Frames of the new helper may already be cached as call sites.
+		callerFrames.Lock()
+		callerFrames.m = map[uintptr]callerFrame{}
+		callerFrames.Unlock()
+	}
+	helpers.Unlock()
+}
+
+// helpers records the functions marked with Helper, by name, and the pcs of
+// the Helper calls seen so far.
+var helpers = struct {
+	sync.RWMutex
+	funcs map[string]bool
+	pcs   map[uintptr]bool
+}{funcs: map[string]bool{}, pcs: map[uintptr]bool{}}
+
+// WithCallerSkip returns a Logger like l that reports the origin of entries n
+// frames further up the stack than l does, for use by wrapper functions:
+//
+//	func logFailure(l logging.Logger, err error) {
+//		logging.WithCallerSkip(l, 1).Errorf("Failed: %v", err)
+//	}
+//
+// Frames are counted from the first one outside this package and Helper
+// functions.  Loggers that do not report origins, or that do not implement a
+// WithCallerSkip(n int) Logger method, are returned unchanged.
+func WithCallerSkip(l Logger, n int) Logger {
+	if cs, ok := l.(interface {
+		WithCallerSkip(n int) Logger
+	}); ok {
+		return cs.WithCallerSkip(n)
+	}
+	return l
+}
+
+// packagePrefix prefixes the names of all functions in this package.
+var packagePrefix = reflect.TypeOf(StdLogger{}).PkgPath() + "."
+
+// redirectedPrefixes prefix the names of the functions of the standard library
+// logging packages whose output this package takes over.  Their frames lie
+// between the logging call and our Loggers, so they are treated as internal.
+var redirectedPrefixes = []string{"log.", "log/slog."}
+
+// callerFrame is a stack frame as seen when looking for the origin of entries.
+type callerFrame struct {
+	runtime.Frame
+	// pc is the pc returned by runtime.Callers, which unlike Frame.PC can be
+	// resolved again with runtime.CallersFrames, as log/slog does.
+	pc uintptr
+	// internal is set for frames in this package, except tests, in Helper
+	// functions and in the packages of redirectedPrefixes.
+	internal bool
+}
+
+// unknownCaller is returned by findCaller if the stack is too shallow.
+var unknownCaller = callerFrame{Frame: runtime.Frame{File: "???", Line: -1}}
+
+// callerFrames caches callerFrames by pc, so that logging from a known call
+// site neither symbolizes nor allocates.
+var callerFrames = struct {
+	sync.RWMutex
+	m map[uintptr]callerFrame
+}{m: map[uintptr]callerFrame{}}
+
+func lookupCallerFrame(pc uintptr) callerFrame {
+	callerFrames.RLock()
+	f, ok := callerFrames.m[pc]
+	callerFrames.RUnlock()
+	if ok {
+		return f
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	frame.Func = nil // not needed; don't retain
+	f = callerFrame{Frame: frame, pc: pc}
+	f.internal = strings.HasPrefix(frame.Function, packagePrefix) && !strings.HasSuffix(frame.File, "_test.go")
+	for _, prefix := range redirectedPrefixes {
+		f.internal = f.internal || strings.HasPrefix(frame.Function, prefix)
+	}
+	helpers.RLock()
+	f.internal = f.internal || helpers.funcs[frame.Function]
+	helpers.RUnlock()
+
+	callerFrames.Lock()
+	callerFrames.m[pc] = f
+	callerFrames.Unlock()
+	return f
+}
+
+// findCaller returns the origin of an entry being logged: the first frame of
+// the stack that is neither in this package nor in a Helper function, then
+// extra frames further up.  skip is the number of frames above findCaller's
+// caller that are known to be internal, which saves looking at them.
+func findCaller(skip, extra int) callerFrame {
+	// Most entries are logged directly from their origin, so start by
+	// unwinding just one frame, which is much cheaper than unwinding several.
+	var pcs [32]uintptr
+	n := runtime.Callers(skip+2, pcs[:1]) // +2 for runtime.Callers and findCaller
+	all := n < 1
+	origin := -1
+	for i := 0; ; i++ {
+		if i == n {
+			if all {
+				return unknownCaller
+			}
+			n, all = runtime.Callers(skip+2, pcs[:]), true
+			if i == n {
+				return unknownCaller
+			}
+		}
+		f := lookupCallerFrame(pcs[i])
+		if origin < 0 && !f.internal {
+			origin = i
+		}
+		if origin >= 0 && i == origin+extra {
+			return f
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"runtime"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// plainWrapper logs without marking itself, so it is the origin of the entry.
+func plainWrapper(log Logger) {
+	log.Info("plain")
+}
+
+func helperWrapper(log Logger) {
+	Helper()
+	log.Info("helper")
+}
+
+func nestedHelper(log Logger) {
+	Helper()
+	helperWrapper(log)
+}
+
+func skippingWrapper(log Logger) {
+	WithCallerSkip(log, 1).Info("skipping")
+}
+
+// nextLine returns the line following the one it is called from.
+func nextLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line + 1
+}
+
+func TestCaller(t *testing.T) {
+	Convey("The origin of entries", t, func() {
+		var c captureWriter
+		log := &StdLogger{Context: "test", Writer: &c, MinLevel: TraceLevel}
+
+		Convey("should be the logging call, with its function", func() {
+			line := nextLine()
+			log.Info("direct")
+			So(c.File, ShouldEndWith, "caller_test.go")
+			So(c.Line, ShouldEqual, line)
+			So(c.Function, ShouldStartWith, packagePrefix+"TestCaller.")
+		})
+		Convey("should be inside wrappers that are not helpers", func() {
+			plainWrapper(log)
+			So(c.Function, ShouldEqual, packagePrefix+"plainWrapper")
+		})
+		Convey("should skip Helper functions", func() {
+			line := nextLine()
+			helperWrapper(log)
+			So(c.Line, ShouldEqual, line)
+			So(c.Function, ShouldStartWith, packagePrefix+"TestCaller.")
+
+			line = nextLine()
+			nestedHelper(log)
+			So(c.Line, ShouldEqual, line)
+		})
+		Convey("should skip frames given to WithCallerSkip", func() {
+			line := nextLine()
+			skippingWrapper(log)
+			So(c.Line, ShouldEqual, line)
+			So(c.Function, ShouldStartWith, packagePrefix+"TestCaller.")
+
+			Convey("through wrapping loggers", func() {
+				cl := &CancellableLogger{Logger: log}
+				line := nextLine()
+				skippingWrapper(NewSampledLogger(cl, SamplePolicy{Burst: 10, Interval: time.Minute}))
+				So(c.Line, ShouldEqual, line)
+
+				mem := NewMemLogger()
+				line = nextLine()
+				skippingWrapper(NewTeeLogger(log, mem))
+				So(c.Line, ShouldEqual, line)
+				So(mem.Entries()[0].Line, ShouldEqual, line)
+			})
+			Convey("and be unknown past the top of the stack", func() {
+				WithCallerSkip(log, 1000).Info("lost")
+				So(c.File, ShouldEqual, "???")
+				So(c.Line, ShouldEqual, -1)
+				So(c.Function, ShouldBeEmpty)
+			})
+		})
+		Convey("should be the caller of package-level functions", func() {
+			var saved Logger
+			System, saved = log, System
+			defer func() { System = saved }()
+			line := nextLine()
+			Warnf("via %s", "System")
+			So(c.Line, ShouldEqual, line)
+		})
+		Convey("should be carried to slog records", func() {
+			var h captureHandler
+			sl := NewSlogLogger(&h, "", TraceLevel)
+			line := nextLine()
+			skippingWrapper(sl)
+			frame, _ := runtime.CallersFrames([]uintptr{h.record.PC}).Next()
+			So(frame.Line, ShouldEqual, line)
+		})
+		Convey("should be written as JSON", func() {
+			var buf bytes.Buffer
+			NewJSONLogger(&buf, "", InfoLevel).Info("hi")
+			So(buf.String(), ShouldContainSubstring, `"function":"`+packagePrefix+"TestCaller.")
+		})
+	})
+}
+
+// captureHandler is a slog.Handler that records the last record handled.
+type captureHandler struct{ record slog.Record }
+
+func (h *captureHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *captureHandler) Handle(_ context.Context, r slog.Record) error {
+	h.record = r
+	return nil
+}
+func (h *captureHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *captureHandler) WithGroup(string) slog.Handler      { return h }
@@ -6,42 +6,95 @@ import (
 
 // CancellableLogger is a logger that can be cancelled.  Once cancelled, all
 // subsequent log calls are dropped.  The cancellation is thread-safe.
+// Loggers derived with With are cancelled along with their parent.
 type CancellableLogger struct {
 	Logger
-	m sync.RWMutex
+	m         sync.RWMutex
+	parent    *CancellableLogger // set for loggers derived with With
+	cancelled bool
 }
 
 func ul(m *sync.RWMutex) func() { m.RLock(); return m.RUnlock }
 
+// mu returns the lock guarding c.  A whole family of derived loggers shares the
+// lock of the original so that cancelling a parent is atomic for its children.
+func (c *CancellableLogger) mu() *sync.RWMutex {
+	for c.parent != nil {
+		c = c.parent
+	}
+	return &c.m
+}
+
+// logger returns the logger to forward to, which is a DiscardLogger if any
+// parent has been cancelled.  The caller must hold c.mu().
+func (c *CancellableLogger) logger() Logger {
+	for p := c.parent; p != nil; p = p.parent {
+		if p.cancelled {
+			return DiscardLogger{}
+		}
+	}
+	return c.Logger
+}
+
 var _ Logger = &CancellableLogger{}
 
-func (c *CancellableLogger) Trace(v ...interface{}) { defer ul(&c.m)(); c.Logger.Trace(v...) }
+func (c *CancellableLogger) Trace(v ...interface{}) { defer ul(c.mu())(); c.logger().Trace(v...) }
 func (c *CancellableLogger) Tracef(f string, a ...interface{}) {
-	defer ul(&c.m)()
-	c.Logger.Tracef(f, a...)
+	defer ul(c.mu())()
+	c.logger().Tracef(f, a...)
 }
-func (c *CancellableLogger) Debug(v ...interface{}) { defer ul(&c.m)(); c.Logger.Debug(v...) }
+func (c *CancellableLogger) Debug(v ...interface{}) { defer ul(c.mu())(); c.logger().Debug(v...) }
 func (c *CancellableLogger) Debugf(f string, a ...interface{}) {
-	defer ul(&c.m)()
-	c.Logger.Debugf(f, a...)
+	defer ul(c.mu())()
+	c.logger().Debugf(f, a...)
 }
-func (c *CancellableLogger) Info(v ...interface{}) { defer ul(&c.m)(); c.Logger.Info(v...) }
+func (c *CancellableLogger) Info(v ...interface{}) { defer ul(c.mu())(); c.logger().Info(v...) }
 func (c *CancellableLogger) Infof(f string, a ...interface{}) {
-	defer ul(&c.m)()
-	c.Logger.Infof(f, a...)
+	defer ul(c.mu())()
+	c.logger().Infof(f, a...)
 }
-func (c *CancellableLogger) Error(v ...interface{}) { defer ul(&c.m)(); c.Logger.Error(v...) }
+func (c *CancellableLogger) Warn(v ...interface{}) { defer ul(c.mu())(); c.logger().Warn(v...) }
+func (c *CancellableLogger) Warnf(f string, a ...interface{}) {
+	defer ul(c.mu())()
+	c.logger().Warnf(f, a...)
+}
+func (c *CancellableLogger) Error(v ...interface{}) { defer ul(c.mu())(); c.logger().Error(v...) }
 func (c *CancellableLogger) Errorf(f string, a ...interface{}) {
-	defer ul(&c.m)()
-	c.Logger.Errorf(f, a...)
+	defer ul(c.mu())()
+	c.logger().Errorf(f, a...)
+}
+func (c *CancellableLogger) fatalf(f string, a ...interface{}) {
+	defer ul(c.mu())()
+	logFatalf(c.logger(), f, a...)
 }
-func (c *CancellableLogger) LogLevel() Level { defer ul(&c.m)(); return c.Logger.LogLevel() }
+func (c *CancellableLogger) LogLevel() Level { defer ul(c.mu())(); return c.logger().LogLevel() }
 func (c *CancellableLogger) SetLogLevel(newLev Level) {
-	defer ul(&c.m)()
-	c.Logger.SetLogLevel(newLev)
+	defer ul(c.mu())()
+	c.logger().SetLogLevel(newLev)
 }
 
-func (c *CancellableLogger) Cancel() { c.m.Lock(); c.Logger = DiscardLogger{}; c.m.Unlock() }
+func (c *CancellableLogger) Enabled(level Level) bool {
+	defer ul(c.mu())()
+	return c.logger().Enabled(level)
+}
+
+func (c *CancellableLogger) With(keyvals ...interface{}) Logger {
+	defer ul(c.mu())()
+	return &CancellableLogger{Logger: c.logger().With(keyvals...), parent: c}
+}
+
+func (c *CancellableLogger) WithCallerSkip(n int) Logger {
+	defer ul(c.mu())()
+	return &CancellableLogger{Logger: WithCallerSkip(c.logger(), n), parent: c}
+}
+
+func (c *CancellableLogger) Cancel() {
+	m := c.mu()
+	m.Lock()
+	c.Logger = DiscardLogger{}
+	c.cancelled = true
+	m.Unlock()
+}
 
 // DiscardLogger is a Logger that drops all logging calls.
 type DiscardLogger struct{}
@@ -52,7 +105,11 @@ func (l DiscardLogger) Debug(vals ...interface{})              {}
 func (l DiscardLogger) Debugf(fmt string, args ...interface{}) {}
 func (l DiscardLogger) Info(vals ...interface{})               {}
 func (l DiscardLogger) Infof(fmt string, args ...interface{})  {}
+func (l DiscardLogger) Warn(vals ...interface{})               {}
+func (l DiscardLogger) Warnf(fmt string, args ...interface{})  {}
 func (l DiscardLogger) Error(vals ...interface{})              {}
 func (l DiscardLogger) Errorf(fmt string, args ...interface{}) {}
 func (l DiscardLogger) LogLevel() Level                        { return ErrorLevel }
 func (l DiscardLogger) SetLogLevel(newLevel Level)             {}
+func (l DiscardLogger) Enabled(level Level) bool               { return false }
+func (l DiscardLogger) With(keyvals ...interface{}) Logger     { return l }
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/fluxio/sync_testing"
+	. "github.com/smartystreets/goconvey/convey"
 )
 
 //  A logger safe for concurrent usage.
@@ -18,10 +19,14 @@ func (l *atomicLogger) Debug(vals ...interface{})              { atomic.AddInt64
 func (l *atomicLogger) Debugf(fmt string, args ...interface{}) { atomic.AddInt64((*int64)(l), 1) }
 func (l *atomicLogger) Info(vals ...interface{})               { atomic.AddInt64((*int64)(l), 1) }
 func (l *atomicLogger) Infof(fmt string, args ...interface{})  { atomic.AddInt64((*int64)(l), 1) }
+func (l *atomicLogger) Warn(vals ...interface{})               { atomic.AddInt64((*int64)(l), 1) }
+func (l *atomicLogger) Warnf(fmt string, args ...interface{})  { atomic.AddInt64((*int64)(l), 1) }
 func (l *atomicLogger) Error(vals ...interface{})              { atomic.AddInt64((*int64)(l), 1) }
 func (l *atomicLogger) Errorf(fmt string, args ...interface{}) { atomic.AddInt64((*int64)(l), 1) }
 func (l *atomicLogger) LogLevel() Level                        { return ErrorLevel }
 func (l *atomicLogger) SetLogLevel(lev Level)                  {}
+func (l *atomicLogger) Enabled(lev Level) bool                 { return true }
+func (l *atomicLogger) With(keyvals ...interface{}) Logger     { return l }
 
 func TestCancellableLogger(t *testing.T) {
 	runtime.GOMAXPROCS(10)
@@ -54,3 +59,28 @@ func TestCancellableLogger(t *testing.T) {
 		t.Error("Artificially failing test to poke race detector.")
 	}
 }
+
+func TestCancellableLoggerWith(t *testing.T) {
+	Convey("Loggers derived from a CancellableLogger", t, func() {
+		var c captureWriter
+		cl := &CancellableLogger{Logger: &StdLogger{Writer: &c, MinLevel: TraceLevel}}
+		derived := cl.With("flow", "f1")
+
+		Convey("should carry the fields", func() {
+			derived.Info("hi")
+			So(c.Fields, ShouldResemble, Fields{{"flow", "f1"}})
+		})
+		Convey("should be cancelled along with their parent", func() {
+			cl.Cancel()
+			derived.With("block", "addFoo").Info("hi")
+			So(c.Args, ShouldBeNil)
+		})
+		Convey("should not cancel their parent", func() {
+			derived.(*CancellableLogger).Cancel()
+			derived.Info("dropped")
+			So(c.Args, ShouldBeNil)
+			cl.Info("kept")
+			So(c.Args, ShouldResemble, []interface{}{"kept"})
+		})
+	})
+}
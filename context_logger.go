@@ -0,0 +1,50 @@
+package logging
+
+import "context"
+
+// contextKey is the key under which NewContext stores a Logger.
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries l.  Use FromContext to retrieve
+// it further down the call chain.
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the Logger carried by ctx, or System if there is none.
+func FromContext(ctx context.Context) Logger {
+	if l, ok := ctx.Value(contextKey{}).(Logger); ok {
+		return l
+	}
+	return System
+}
+
+// ContextWith returns a copy of ctx whose Logger additionally attaches the
+// given key/value pairs to every entry.  For example:
+//
+//	ctx = logging.ContextWith(ctx, "request", reqID, "block", block.Name)
+func ContextWith(ctx context.Context, keyvals ...interface{}) context.Context {
+	return NewContext(ctx, FromContext(ctx).With(keyvals...))
+}
+
+// Convenience accessors to the logger carried by a context.
+func TraceContext(ctx context.Context, vals ...interface{}) { FromContext(ctx).Trace(vals...) }
+func DebugContext(ctx context.Context, vals ...interface{}) { FromContext(ctx).Debug(vals...) }
+func InfoContext(ctx context.Context, vals ...interface{})  { FromContext(ctx).Info(vals...) }
+func WarnContext(ctx context.Context, vals ...interface{})  { FromContext(ctx).Warn(vals...) }
+func ErrorContext(ctx context.Context, vals ...interface{}) { FromContext(ctx).Error(vals...) }
+func TracefContext(ctx context.Context, fmt string, args ...interface{}) {
+	FromContext(ctx).Tracef(fmt, args...)
+}
+func DebugfContext(ctx context.Context, fmt string, args ...interface{}) {
+	FromContext(ctx).Debugf(fmt, args...)
+}
+func InfofContext(ctx context.Context, fmt string, args ...interface{}) {
+	FromContext(ctx).Infof(fmt, args...)
+}
+func WarnfContext(ctx context.Context, fmt string, args ...interface{}) {
+	FromContext(ctx).Warnf(fmt, args...)
+}
+func ErrorfContext(ctx context.Context, fmt string, args ...interface{}) {
+	FromContext(ctx).Errorf(fmt, args...)
+}
@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestContextLogger(t *testing.T) {
+	Convey("Loggers in a context.Context", t, func() {
+		var c captureWriter
+		log := &StdLogger{Context: "Flow=f1", Writer: &c, MinLevel: TraceLevel}
+		ctx := NewContext(context.Background(), log)
+
+		Convey("should be retrieved by FromContext", func() {
+			So(FromContext(ctx), ShouldEqual, log)
+		})
+		Convey("should fall back to System", func() {
+			So(FromContext(context.Background()), ShouldEqual, System)
+		})
+		Convey("should accumulate fields with ContextWith", func() {
+			ctx = ContextWith(ctx, "request", 17)
+			ctx = ContextWith(ctx, "block", "addFoo")
+			InfofContext(ctx, "Computing %d things", 5)
+			So(c.Context, ShouldEqual, "Flow=f1")
+			So(c.Fields, ShouldResemble, Fields{{"request", 17}, {"block", "addFoo"}})
+			So(Entry(c).Message(), ShouldEqual, "Computing 5 things")
+		})
+		Convey("should log at the right level and call point", func() {
+			WarnContext(ctx, "careful")
+			So(c.Level, ShouldEqual, WarnLevel)
+			So(c.File, ShouldContainSubstring, "context_logger_test.go")
+			ErrorfContext(ctx, "bad %s", "news")
+			So(c.Level, ShouldEqual, ErrorLevel)
+			So(c.File, ShouldContainSubstring, "context_logger_test.go")
+		})
+		Convey("should report the call point when falling back to System", func() {
+			var sc captureWriter
+			var saved Logger
+			System, saved = &StdLogger{Writer: &sc, MinLevel: TraceLevel}, System
+			defer func() { System = saved }()
+			DebugContext(context.Background(), "hi")
+			So(sc.File, ShouldContainSubstring, "context_logger_test.go")
+		})
+	})
+}
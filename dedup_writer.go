@@ -0,0 +1,103 @@
+package logging
+
+import (
+	"sync"
+	"time"
+)
+
+// DedupWriter is a Writer that collapses runs of identical entries.  An entry is
+// identical to the previous one if it has the same level, context, file, line
+// and rendered message; fields are not compared.  The first entry of a run is
+// written straight away and the rest are counted.  When the run ends, or
+// Timeout after its first repeat, a single "last message repeated N times"
+// entry is written in their place.
+//
+// DedupWriter is safe for concurrent use, so one can be shared by the loggers
+// of a TeeLogger or by several StdLoggers.
+type DedupWriter struct {
+	writer  Writer
+	timeout time.Duration
+
+	mutex   sync.Mutex
+	last    Entry
+	lastMsg string
+	repeats int
+	// lastTime is the time of the most recent repeat.
+	lastTime time.Time
+	// timer reports the repeats of the current run.  timerGen identifies it,
+	// so that a stopped timer that fired anyway does not cut a later run short.
+	timer    *time.Timer
+	timerGen uint64
+}
+
+// NewDedupWriter returns a DedupWriter that writes to w.  If timeout is
+// positive, repeats are reported at least that often while a run lasts.
+func NewDedupWriter(w Writer, timeout time.Duration) *DedupWriter {
+	return &DedupWriter{writer: w, timeout: timeout}
+}
+
+func (d *DedupWriter) Write(e Entry) error {
+	msg := e.Message()
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.lastMsg == msg && d.last.Level == e.Level && d.last.Context == e.Context &&
+		d.last.File == e.File && d.last.Line == e.Line {
+		d.repeats++
+		d.lastTime = e.Time
+		if d.timeout > 0 && d.timer == nil {
+			d.timerGen++
+			gen := d.timerGen
+			d.timer = time.AfterFunc(d.timeout, func() { d.timedFlush(gen) })
+		}
+		return nil
+	}
+
+	err := d.flushRepeats()
+	d.last, d.lastMsg = e, msg
+	if werr := d.writer.Write(e); werr != nil {
+		err = werr
+	}
+	return err
+}
+
+// Flush writes the count of repeats of the last entry, if there are any.
+func (d *DedupWriter) Flush() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.flushRepeats()
+}
+
+func (d *DedupWriter) timedFlush(gen uint64) {
+	d.mutex.Lock()
+	if d.timer == nil || gen != d.timerGen {
+		d.mutex.Unlock()
+		return // stopped after it fired
+	}
+	d.timer = nil
+	err := d.flushRepeats()
+	d.mutex.Unlock()
+	// Report without holding the mutex, since the System logger may write
+	// through d.
+	if err != nil {
+		Errorf("Failed to write repeat count: %v", err)
+	}
+}
+
+// flushRepeats writes the count of repeats, if any, and resets it.  Later
+// repeats of the same entry start a new count.  d.mutex must be held.
+func (d *DedupWriter) flushRepeats() error {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	if d.repeats == 0 {
+		return nil
+	}
+	e := d.last
+	e.Time = d.lastTime
+	e.Fmt = "last message repeated %d times"
+	e.Args = []interface{}{d.repeats}
+	d.repeats = 0
+	return d.writer.Write(e)
+}
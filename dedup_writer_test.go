@@ -0,0 +1,139 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDedupWriter(t *testing.T) {
+	Convey("DedupWriter", t, func() {
+		mem := NewMemLogger()
+		d := NewDedupWriter(mem.ring, 0)
+		log := &StdLogger{Context: "Flow=f1", Writer: d, MinLevel: TraceLevel}
+		msgs := func() []string {
+			var out []string
+			for _, m := range mem.ExtractMsgs() {
+				out = append(out, m.Loglevel.String()+" "+m.Msg)
+			}
+			return out
+		}
+		heartbeat := func(level Level) { logAtLevel(log, level, "heartbeat") }
+
+		Convey("should collapse consecutive identical entries", func() {
+			heartbeat(InfoLevel)
+			heartbeat(InfoLevel)
+			heartbeat(InfoLevel)
+			log.Error("real event")
+			heartbeat(InfoLevel)
+			So(msgs(), ShouldResemble, []string{
+				"I heartbeat",
+				"I last message repeated 2 times",
+				"E real event",
+				"I heartbeat",
+			})
+		})
+		Convey("should compare level, origin and message", func() {
+			heartbeat(InfoLevel)
+			heartbeat(WarnLevel)
+			log.Info("heartbeat")
+			log.Info("heartbeat")
+			log.With("k", "v").Info("heartbeat")
+			So(msgs(), ShouldResemble, []string{
+				"I heartbeat",
+				"W heartbeat",
+				"I heartbeat",
+				"I heartbeat",
+				"I heartbeat",
+			})
+		})
+		Convey("should report repeats on Flush", func() {
+			heartbeat(InfoLevel)
+			heartbeat(InfoLevel)
+			So(d.Flush(), ShouldBeNil)
+			heartbeat(InfoLevel)
+			So(msgs(), ShouldResemble, []string{"I heartbeat", "I last message repeated 1 times"})
+		})
+		Convey("should report repeats after the timeout", func() {
+			d := NewDedupWriter(mem.ring, 10*time.Millisecond)
+			log.Writer = d
+			for i := 0; i < 3; i++ {
+				heartbeat(DebugLevel)
+			}
+			for i := 0; i < 100 && len(mem.Entries()) < 2; i++ {
+				time.Sleep(5 * time.Millisecond)
+			}
+			So(msgs(), ShouldResemble, []string{"D heartbeat", "D last message repeated 2 times"})
+		})
+	})
+
+	Convey("A DedupWriter timer that was stopped after firing", t, func() {
+		mem := NewMemLogger()
+		d := NewDedupWriter(mem.ring, time.Hour)
+		log := &StdLogger{Writer: d, MinLevel: TraceLevel}
+		heartbeat := func() { log.Info("heartbeat") }
+		heartbeat()
+		heartbeat()
+		stale := d.timerGen
+		So(d.Flush(), ShouldBeNil)
+		heartbeat()
+		heartbeat()
+		d.timedFlush(stale)
+		So(mem.Entries(), ShouldHaveLength, 2)
+		So(d.timer, ShouldNotBeNil)
+		So(d.Flush(), ShouldBeNil)
+		So(mem.ExtractMsgs()[2].Msg, ShouldEqual, "last message repeated 2 times")
+	})
+
+	Convey("A DedupWriter used by the System logger", t, func() {
+		saved := System
+		defer func() { System = saved }()
+		mem := NewMemLogger()
+		d := NewDedupWriter(repeatFailingWriter{mem.ring}, 10*time.Millisecond)
+		System = &StdLogger{Writer: d, MinLevel: TraceLevel}
+		for i := 0; i < 2; i++ {
+			Info("heartbeat")
+		}
+		for i := 0; i < 100 && len(mem.Entries()) < 2; i++ {
+			time.Sleep(5 * time.Millisecond)
+		}
+		msgs := mem.ExtractMsgs()
+		So(msgs, ShouldHaveLength, 2)
+		So(msgs[1].Msg, ShouldStartWith, "Failed to write repeat count: ")
+	})
+
+	Convey("DedupWriters behind a TeeLogger", t, func() {
+		var buf1, buf2 bytes.Buffer
+		d1, d2 := NewDedupWriter(&TextWriter{Writer: &buf1}, 0), NewDedupWriter(&TextWriter{Writer: &buf2}, 0)
+		tee := NewTeeLogger(
+			&StdLogger{Writer: d1, MinLevel: TraceLevel},
+			&StdLogger{Writer: d2, MinLevel: TraceLevel},
+		)
+		logIt := func() { tee.Info("ping") }
+		logIt()
+		logIt()
+		logIt()
+		tee.Warn("done")
+		for _, buf := range []*bytes.Buffer{&buf1, &buf2} {
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			So(lines, ShouldHaveLength, 3)
+			So(lines[0], ShouldEndWith, ": ping")
+			So(lines[1], ShouldEndWith, ": last message repeated 2 times")
+			So(lines[2], ShouldEndWith, ": done")
+		}
+	})
+}
+
+// repeatFailingWriter fails to write the repeat counts of a DedupWriter.
+type repeatFailingWriter struct{ Writer }
+
+func (w repeatFailingWriter) Write(e Entry) error {
+	if strings.HasPrefix(e.Fmt, "last message repeated") {
+		return errors.New("write failed")
+	}
+	return w.Writer.Write(e)
+}
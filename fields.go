@@ -0,0 +1,162 @@
+package logging
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Field is a single structured key/value pair attached to log entries.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Fields is an ordered list of key/value pairs.  Order is preserved so that
+// rendered output is stable and reads in the order the fields were added.
+type Fields []Field
+
+// missingValue is used when With is called with an odd number of arguments.
+const missingValue = "(MISSING)"
+
+// With returns a copy of f extended with the given alternating keys and
+// values.  Keys that are not strings are converted with fmt.Sprint.  If a key
+// is already present, its value is replaced in place.  f itself is never
+// modified, so it is safe to share between loggers.
+func (f Fields) With(keyvals ...interface{}) Fields {
+	if len(keyvals) == 0 {
+		return f
+	}
+	out := make(Fields, len(f), len(f)+(len(keyvals)+1)/2)
+	copy(out, f)
+	for i := 0; i < len(keyvals); i += 2 {
+		key, ok := keyvals[i].(string)
+		if !ok {
+			key = fmt.Sprint(keyvals[i])
+		}
+		var val interface{} = missingValue
+		if i+1 < len(keyvals) {
+			val = keyvals[i+1]
+		}
+		out = out.set(key, val)
+	}
+	return out
+}
+
+func (f Fields) set(key string, val interface{}) Fields {
+	for i := range f {
+		if f[i].Key == key {
+			f[i].Value = val
+			return f
+		}
+	}
+	return append(f, Field{key, val})
+}
+
+// keyvals flattens the fields back into alternating keys and values, suitable
+// for passing to Logger.With.
+func (f Fields) keyvals() []interface{} {
+	kv := make([]interface{}, 0, 2*len(f))
+	for _, field := range f {
+		kv = append(kv, field.Key, field.Value)
+	}
+	return kv
+}
+
+// String renders the fields as space-separated key=value pairs.  Values that
+// contain spaces, quotes, '=' or non-printable characters are quoted.
+func (f Fields) String() string { return string(f.appendTo(nil)) }
+
+// appendTo appends the fields as rendered by String to b.
+func (f Fields) appendTo(b []byte) []byte {
+	for i, field := range f {
+		if i > 0 {
+			b = append(b, ' ')
+		}
+		b = appendFieldValue(b, field.Key)
+		b = append(b, '=')
+		switch v := field.Value.(type) {
+		case string:
+			b = appendFieldValue(b, v)
+		case int:
+			b = strconv.AppendInt(b, int64(v), 10)
+		case int64:
+			b = strconv.AppendInt(b, v, 10)
+		case bool:
+			b = strconv.AppendBool(b, v)
+		default:
+			b = appendFieldValue(b, fmt.Sprint(v))
+		}
+	}
+	return b
+}
+
+// appendFieldValue appends s to b, quoted if it cannot be represented as a bare
+// word in a key=value list.
+func appendFieldValue(b []byte, s string) []byte {
+	if needsQuoting(s) {
+		return strconv.AppendQuote(b, s)
+	}
+	return append(b, s...)
+}
+
+func needsQuoting(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r == utf8.RuneError || r == '=' || r == '"' || r == ']' || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFields parses key=value pairs as rendered by Fields.String.  Values
+// are returned as strings.
+func parseFields(s string) (Fields, error) {
+	var f Fields
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return f, nil
+		}
+		key, rest, err := scanFieldToken(s)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(rest, "=") {
+			return nil, fmt.Errorf("missing '=' after key %q", key)
+		}
+		val, rest, err := scanFieldToken(rest[1:])
+		if err != nil {
+			return nil, err
+		}
+		f = append(f, Field{key, val})
+		s = rest
+	}
+}
+
+// scanFieldToken reads a single, possibly quoted, key or value from the start
+// of s and returns it along with the remainder of s.
+func scanFieldToken(s string) (tok, rest string, err error) {
+	if !strings.HasPrefix(s, `"`) {
+		end := strings.IndexAny(s, " =")
+		if end < 0 {
+			end = len(s)
+		}
+		return s[:end], s[end:], nil
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			tok, err = strconv.Unquote(s[:i+1])
+			return tok, s[i+1:], err
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted string %q", s)
+}
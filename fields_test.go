@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFields(t *testing.T) {
+	Convey("Fields", t, func() {
+		Convey("should be built from alternating keys and values", func() {
+			f := Fields(nil).With("a", 1, "b", "x")
+			So(f, ShouldResemble, Fields{{"a", 1}, {"b", "x"}})
+		})
+		Convey("should not modify the receiver", func() {
+			base := Fields(nil).With("a", 1)
+			base.With("a", 2, "b", 3)
+			So(base, ShouldResemble, Fields{{"a", 1}})
+		})
+		Convey("should replace existing keys in place", func() {
+			f := Fields(nil).With("a", 1, "b", 2).With("a", 3)
+			So(f, ShouldResemble, Fields{{"a", 3}, {"b", 2}})
+		})
+		Convey("should handle odd arguments and non-string keys", func() {
+			f := Fields(nil).With(7, "seven", "dangling")
+			So(f, ShouldResemble, Fields{{"7", "seven"}, {"dangling", missingValue}})
+		})
+		Convey("should render as key=value pairs, quoting where needed", func() {
+			f := Fields{{"a", 1}, {"b", "two words"}, {"c", ""}, {"d", "x=y"}, {"e", "line\nbreak"}}
+			So(f.String(), ShouldEqual, `a=1 b="two words" c="" d="x=y" e="line\nbreak"`)
+		})
+	})
+}
+
+func TestParseFields(t *testing.T) {
+	Convey("parseFields", t, func() {
+		Convey("should parse the output of Fields.String", func() {
+			in := Fields{{"a", "1"}, {"b", "two words"}, {"c", ""}, {"d", "x=y"}, {"e", "line\nbreak"}, {"f", "[x]"}}
+			out, err := parseFields(in.String())
+			So(err, ShouldBeNil)
+			So(out, ShouldResemble, in)
+		})
+		Convey("should accept an empty string", func() {
+			out, err := parseFields("")
+			So(err, ShouldBeNil)
+			So(out, ShouldBeNil)
+		})
+		Convey("should reject malformed input", func() {
+			_, err := parseFields("a")
+			So(err, ShouldNotBeNil)
+			_, err = parseFields(`a="unterminated`)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,309 @@
+package logging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GELFCompression selects how a GELFWriter compresses UDP messages.
+type GELFCompression int
+
+const (
+	GELFNoCompression GELFCompression = iota
+	GELFGzip
+	GELFZlib
+)
+
+// DefaultGELFChunkSize is the default size of GELF UDP datagrams, which fits
+// the MTU of most networks.
+const DefaultGELFChunkSize = 1420
+
+// GELFWriter is a Writer that sends entries to Graylog, or any other server
+// that accepts GELF 1.1 messages.  For example:
+//
+//	w := &GELFWriter{Network: "udp", Addr: "graylog:12201", Compression: GELFGzip}
+//	defer w.Close()
+//	logger := &StdLogger{Context: "app", Writer: w, MinLevel: InfoLevel}
+//
+// The first line of the message is sent as short_message, and the whole
+// message as full_message if it has more than one line.  level is the syslog
+// severity, as for SyslogWriter.  The entry's origin and context are sent as
+// the additional fields _file, _line, _function and _context, and its fields
+// as additional fields named after them, with characters that GELF does not
+// allow replaced by '_'.  A field named "id", which GELF reserves, is sent as
+// "_id_", and fields named "file", "line", "function" or "context" likewise get
+// a trailing '_', so that they cannot replace the entry's own.  Field values
+// other than numbers are sent as strings.
+//
+// Network is "udp" or "tcp", and Addr the server's host:port.  Over UDP, each
+// message is compressed as given by Compression and, if larger than ChunkSize,
+// split into at most 128 chunks.  Over TCP, messages are uncompressed and
+// terminated by a null byte.  The connection is made on first Write, and
+// remade once if sending fails.
+//
+// Host defaults to the machine's host name, ChunkSize to DefaultGELFChunkSize
+// and Timeout, which bounds connecting and each write, to 10 seconds.
+type GELFWriter struct {
+	Network     string
+	Addr        string
+	Host        string
+	Compression GELFCompression
+	ChunkSize   int
+	Timeout     time.Duration
+
+	once sync.Once
+	host string
+
+	mutex  sync.Mutex
+	conn   net.Conn
+	stream bool
+	closed bool
+}
+
+var (
+	errGELFWriterClosed = errors.New("GELFWriter is closed")
+	errGELFTooLarge     = errors.New("GELF message needs more than 128 chunks")
+)
+
+// gelfChunkHeaderSize is the size of the header of each chunk: the magic bytes
+// 0x1e 0x0f, an 8-byte message ID, and the chunk's sequence number and count.
+const gelfChunkHeaderSize = 12
+
+func (w *GELFWriter) Write(e Entry) error {
+	w.once.Do(w.setDefaults)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(w.gelfMessage(e)); err != nil {
+		return err
+	}
+	msg := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.closed {
+		return errGELFWriterClosed
+	}
+	err := w.send(msg)
+	if err != nil && err != errGELFTooLarge {
+		// The server may have restarted.
+		w.disconnect()
+		err = w.send(msg)
+		if err != nil {
+			w.disconnect()
+		}
+	}
+	return err
+}
+
+// Close closes the connection to the server.  Later writes fail.
+func (w *GELFWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.closed = true
+	return w.disconnect()
+}
+
+func (w *GELFWriter) setDefaults() {
+	w.host = w.Host
+	if w.host == "" {
+		w.host, _ = os.Hostname()
+	}
+}
+
+func (w *GELFWriter) timeout() time.Duration {
+	if w.Timeout > 0 {
+		return w.Timeout
+	}
+	return defaultNetworkTimeout
+}
+
+func (w *GELFWriter) chunkSize() int {
+	if w.ChunkSize > gelfChunkHeaderSize {
+		return w.ChunkSize
+	}
+	return DefaultGELFChunkSize
+}
+
+// gelfMessage returns the GELF message for e.
+func (w *GELFWriter) gelfMessage(e Entry) map[string]interface{} {
+	full := e.Message()
+	short := full
+	if i := strings.IndexByte(full, '\n'); i >= 0 {
+		short = full[:i]
+	} else {
+		full = ""
+	}
+	if short == "" {
+		short = "-" // GELF requires a non-empty short_message
+	}
+	m := map[string]interface{}{
+		"version":       "1.1",
+		"host":          w.host,
+		"short_message": short,
+		"level":         syslogSeverity(e.Level),
+	}
+	if full != "" {
+		m["full_message"] = full
+	}
+	if !e.Time.IsZero() {
+		m["timestamp"] = json.Number(strconv.FormatFloat(float64(e.Time.UnixNano()/1e6)/1e3, 'f', 3, 64))
+	}
+	if e.File != "" {
+		m["_file"] = e.File
+	}
+	if e.Line != -1 {
+		m["_line"] = e.Line
+	}
+	if e.Function != "" {
+		m["_function"] = e.Function
+	}
+	if e.Context != "" {
+		m["_context"] = e.Context
+	}
+	for _, f := range e.Fields {
+		m[gelfFieldName(f.Key)] = gelfValue(f.Value)
+	}
+	return m
+}
+
+// gelfReservedNames are the names of fields that GELF reserves or that
+// GELFWriter sends itself.
+var gelfReservedNames = map[string]bool{"id": true, "file": true, "line": true, "function": true, "context": true}
+
+// gelfFieldName returns the name of the additional field for key.
+func gelfFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			return r
+		}
+		return '_'
+	}, key)
+	if gelfReservedNames[name] {
+		return "_" + name + "_"
+	}
+	return "_" + name
+}
+
+// gelfValue returns v if it is a number that JSON can represent, or else its
+// text.
+func gelfValue(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		if f := rv.Float(); math.IsNaN(f) || math.IsInf(f, 0) {
+			break
+		}
+		fallthrough
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if _, isErr := v.(error); !isErr {
+			if _, isStringer := v.(fmt.Stringer); !isStringer {
+				return v
+			}
+		}
+	}
+	return fieldText(v)
+}
+
+// send sends a message, connecting first if necessary.  w.mutex must be held.
+func (w *GELFWriter) send(msg []byte) error {
+	if w.conn == nil {
+		conn, err := net.DialTimeout(w.Network, w.Addr, w.timeout())
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+		switch w.Network {
+		case "tcp", "tcp4", "tcp6":
+			w.stream = true
+		default:
+			w.stream = false
+		}
+	}
+	if err := w.conn.SetWriteDeadline(time.Now().Add(w.timeout())); err != nil {
+		return err
+	}
+	if w.stream {
+		_, err := w.conn.Write(append(msg, 0))
+		return err
+	}
+
+	msg, err := w.compress(msg)
+	if err != nil {
+		return err
+	}
+	size := w.chunkSize()
+	if len(msg) <= size {
+		_, err := w.conn.Write(msg)
+		return err
+	}
+	dataSize := size - gelfChunkHeaderSize
+	count := (len(msg) + dataSize - 1) / dataSize
+	if count > 128 {
+		return errGELFTooLarge
+	}
+	chunk := make([]byte, gelfChunkHeaderSize, size)
+	chunk[0], chunk[1] = 0x1e, 0x0f
+	if _, err := rand.Read(chunk[2:10]); err != nil {
+		return err
+	}
+	chunk[11] = byte(count)
+	for i := 0; i < count; i++ {
+		chunk[10] = byte(i)
+		data := msg[i*dataSize:]
+		if len(data) > dataSize {
+			data = data[:dataSize]
+		}
+		if _, err := w.conn.Write(append(chunk[:gelfChunkHeaderSize], data...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compress compresses msg as given by w.Compression.
+func (w *GELFWriter) compress(msg []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var zw io.WriteCloser
+	switch w.Compression {
+	case GELFGzip:
+		zw = gzip.NewWriter(&buf)
+	case GELFZlib:
+		zw = zlib.NewWriter(&buf)
+	default:
+		return msg, nil
+	}
+	if _, err := zw.Write(msg); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// disconnect closes the connection, if any.  w.mutex must be held.
+func (w *GELFWriter) disconnect() error {
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
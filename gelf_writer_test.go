@@ -0,0 +1,225 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// gelfServer is a stand-in for a Graylog UDP input.  It reassembles chunked
+// messages and decompresses them.
+type gelfServer struct {
+	conn     *net.UDPConn
+	chunks   map[string][][]byte
+	datagram int // number of datagrams received
+}
+
+func newGELFServer() *gelfServer {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	So(err, ShouldBeNil)
+	return &gelfServer{conn: conn, chunks: map[string][][]byte{}}
+}
+
+// read returns the next complete message.
+func (s *gelfServer) read() (map[string]interface{}, error) {
+	buf := make([]byte, 65536)
+	for {
+		s.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, err := s.conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		s.datagram++
+		p := append([]byte(nil), buf[:n]...)
+		if bytes.HasPrefix(p, []byte{0x1e, 0x0f}) {
+			id, seq, count := string(p[2:10]), int(p[10]), int(p[11])
+			if s.chunks[id] == nil {
+				s.chunks[id] = make([][]byte, count)
+			}
+			s.chunks[id][seq] = p[12:]
+			var whole []byte
+			for _, chunk := range s.chunks[id] {
+				if chunk == nil {
+					whole = nil
+					break
+				}
+				whole = append(whole, chunk...)
+			}
+			if whole == nil {
+				continue
+			}
+			delete(s.chunks, id)
+			p = whole
+		}
+		return decodeGELF(p)
+	}
+}
+
+func decodeGELF(p []byte) (map[string]interface{}, error) {
+	var r io.Reader = bytes.NewReader(p)
+	var err error
+	switch {
+	case bytes.HasPrefix(p, []byte{0x1f, 0x8b}):
+		r, err = gzip.NewReader(r)
+	case p[0] == 0x78:
+		r, err = zlib.NewReader(r)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p, err = ioutil.ReadAll(r); err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(p))
+	d.UseNumber()
+	return m, d.Decode(&m)
+}
+
+func TestGELFWriter(t *testing.T) {
+	Convey("GELFWriter", t, func() {
+		entry := Entry{
+			Level:    ErrorLevel,
+			Time:     time.Date(2016, 5, 23, 21, 21, 18, 901234000, time.UTC),
+			File:     "/src/flow/block.go",
+			Line:     12,
+			Function: "flow.(*Block).Add",
+			Context:  "Flow=f1",
+			Fmt:      "Failed: %v\n%s",
+			Args:     []interface{}{"boom", "stack <trace>"},
+			Fields:   Fields{{"block", "addFoo"}, {"id", 7}, {"took", 1500 * time.Millisecond}, {"odd key", 2.5}},
+		}
+		s := newGELFServer()
+		defer s.conn.Close()
+
+		Convey("should encode entries as GELF 1.1", func() {
+			w := &GELFWriter{Network: "udp", Addr: s.conn.LocalAddr().String(), Host: "host1"}
+			defer w.Close()
+			So(w.Write(entry), ShouldBeNil)
+			m, err := s.read()
+			So(err, ShouldBeNil)
+			So(m, ShouldResemble, map[string]interface{}{
+				"version":       "1.1",
+				"host":          "host1",
+				"short_message": "Failed: boom",
+				"full_message":  "Failed: boom\nstack <trace>",
+				"timestamp":     json.Number("1464038478.901"),
+				"level":         json.Number("3"),
+				"_file":         "/src/flow/block.go",
+				"_line":         json.Number("12"),
+				"_function":     "flow.(*Block).Add",
+				"_context":      "Flow=f1",
+				"_block":        "addFoo",
+				"_id_":          json.Number("7"),
+				"_took":         "1.5s",
+				"_odd_key":      json.Number("2.5"),
+			})
+
+			Convey("and omit what is unknown", func() {
+				So(w.Write(Entry{Level: InfoLevel, Line: -1}), ShouldBeNil)
+				m, err := s.read()
+				So(err, ShouldBeNil)
+				So(m, ShouldResemble, map[string]interface{}{
+					"version":       "1.1",
+					"host":          "host1",
+					"short_message": "-",
+					"level":         json.Number("6"),
+				})
+			})
+		})
+
+		Convey("should not let fields replace the entry's origin and context", func() {
+			w := &GELFWriter{Network: "udp", Addr: s.conn.LocalAddr().String(), Host: "host1"}
+			defer w.Close()
+			e := entry
+			e.Fields = Fields{{"file", "f"}, {"line", 1}, {"function", "fn"}, {"context", "c"}}
+			So(w.Write(e), ShouldBeNil)
+			m, err := s.read()
+			So(err, ShouldBeNil)
+			So(m["_file"], ShouldEqual, "/src/flow/block.go")
+			So(m["_line"], ShouldEqual, json.Number("12"))
+			So(m["_function"], ShouldEqual, "flow.(*Block).Add")
+			So(m["_context"], ShouldEqual, "Flow=f1")
+			So(m["_file_"], ShouldEqual, "f")
+			So(m["_line_"], ShouldEqual, json.Number("1"))
+			So(m["_function_"], ShouldEqual, "fn")
+			So(m["_context_"], ShouldEqual, "c")
+		})
+		Convey("should send floats that JSON cannot represent as text", func() {
+			w := &GELFWriter{Network: "udp", Addr: s.conn.LocalAddr().String(), Host: "host1"}
+			defer w.Close()
+			e := entry
+			e.Fields = Fields{{"nan", math.NaN()}, {"inf", float32(math.Inf(-1))}}
+			So(w.Write(e), ShouldBeNil)
+			m, err := s.read()
+			So(err, ShouldBeNil)
+			So(m["_nan"], ShouldEqual, "NaN")
+			So(m["_inf"], ShouldEqual, "-Inf")
+		})
+
+		for _, compression := range []GELFCompression{GELFNoCompression, GELFGzip, GELFZlib} {
+			Convey(fmt.Sprintf("should chunk large messages with compression %d", compression), func() {
+				w := &GELFWriter{Network: "udp", Addr: s.conn.LocalAddr().String(), Compression: compression, ChunkSize: 100}
+				defer w.Close()
+				var words []string
+				for i := 0; i < 500; i++ {
+					words = append(words, fmt.Sprint(i*7919))
+				}
+				long := strings.Join(words, " ")
+				So(w.Write(Entry{Level: InfoLevel, Line: -1, Args: []interface{}{long}}), ShouldBeNil)
+				m, err := s.read()
+				So(err, ShouldBeNil)
+				So(m["short_message"], ShouldEqual, long)
+				So(s.datagram, ShouldBeGreaterThan, 1)
+
+				Convey("up to 128 chunks", func() {
+					huge := strings.Repeat(long, 10)
+					err := w.Write(Entry{Level: InfoLevel, Line: -1, Args: []interface{}{huge}})
+					if compression == GELFNoCompression {
+						So(err, ShouldEqual, errGELFTooLarge)
+					}
+				})
+			})
+		}
+
+		Convey("should send null-delimited messages over TCP", func() {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			So(err, ShouldBeNil)
+			defer ln.Close()
+			w := &GELFWriter{Network: "tcp", Addr: ln.Addr().String(), Compression: GELFGzip}
+			defer w.Close()
+			So(w.Write(entry), ShouldBeNil)
+			So(w.Write(Entry{Level: WarnLevel, Line: -1, Args: []interface{}{"second"}}), ShouldBeNil)
+
+			conn, err := ln.Accept()
+			So(err, ShouldBeNil)
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			for _, short := range []string{"Failed: boom", "second"} {
+				p, err := r.ReadBytes(0)
+				So(err, ShouldBeNil)
+				m, err := decodeGELF(p[:len(p)-1])
+				So(err, ShouldBeNil)
+				So(m["short_message"], ShouldEqual, short)
+			}
+		})
+
+		Convey("should fail once closed", func() {
+			w := &GELFWriter{Network: "udp", Addr: s.conn.LocalAddr().String()}
+			So(w.Close(), ShouldBeNil)
+			So(w.Write(entry), ShouldEqual, errGELFWriterClosed)
+		})
+	})
+}
@@ -0,0 +1,105 @@
+//go:build linux
+
+package logging
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// sysMemfdCreate is the number of the memfd_create system call, which the
+// syscall package does not define on every architecture, or 0 if unknown.
+var sysMemfdCreate = map[string]uintptr{
+	"386":      356,
+	"amd64":    319,
+	"arm":      385,
+	"arm64":    279,
+	"loong64":  279,
+	"mips64":   5314,
+	"mips64le": 5314,
+	"ppc64":    360,
+	"ppc64le":  360,
+	"riscv64":  279,
+	"s390x":    350,
+}[runtime.GOARCH]
+
+const (
+	mfdCloexec      = 0x1
+	mfdAllowSealing = 0x2
+	fAddSeals       = 1033
+	// F_SEAL_SEAL | F_SEAL_SHRINK | F_SEAL_GROW | F_SEAL_WRITE
+	sealAll = 0x1 | 0x2 | 0x4 | 0x8
+)
+
+// sendJournalFile passes p to journald in a file, for entries too large to be
+// sent as a datagram.
+func sendJournalFile(conn *net.UnixConn, p []byte) error {
+	f, err := memfd(p)
+	if err != nil {
+		f, err = shmFile(p)
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// The net package refuses to send control messages on connected datagram
+	// sockets, so send it directly.
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	rights := syscall.UnixRights(int(f.Fd()))
+	var sendErr error
+	if err := raw.Write(func(fd uintptr) bool {
+		sendErr = syscall.Sendmsg(int(fd), nil, rights, nil, 0)
+		return sendErr != syscall.EAGAIN
+	}); err != nil {
+		return err
+	}
+	return sendErr
+}
+
+// memfd returns a sealed memfd holding p.
+func memfd(p []byte) (*os.File, error) {
+	if sysMemfdCreate == 0 {
+		return nil, syscall.ENOSYS
+	}
+	name, err := syscall.BytePtrFromString("journal-entry")
+	if err != nil {
+		return nil, err
+	}
+	fd, _, errno := syscall.Syscall(sysMemfdCreate, uintptr(unsafe.Pointer(name)), mfdCloexec|mfdAllowSealing, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	f := os.NewFile(fd, "journal-entry")
+	if _, err := f.Write(p); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, fAddSeals, sealAll); errno != 0 {
+		f.Close()
+		return nil, errno
+	}
+	return f, nil
+}
+
+// shmFile returns an unlinked file in /dev/shm holding p.  journald accepts
+// these from kernels that predate memfd.
+func shmFile(p []byte) (*os.File, error) {
+	f, err := ioutil.TempFile("/dev/shm", "journal-entry.")
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(f.Name())
+	if _, err := f.Write(p); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,64 @@
+//go:build linux
+
+package logging
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestJournalFile(t *testing.T) {
+	Convey("Entries too large for a datagram should be passed in a file", t, func() {
+		dir, err := ioutil.TempDir("", "journal_file_test")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		conn, path := listenJournal(dir)
+		defer conn.Close()
+
+		w := &JournalWriter{Path: path, Identifier: "app"}
+		defer w.Close()
+		huge := strings.Repeat("x", 4<<20)
+		So(w.Write(Entry{Level: ErrorLevel, Line: -1, Args: []interface{}{huge}}), ShouldBeNil)
+
+		oob := make([]byte, syscall.CmsgSpace(4))
+		n, oobn, _, _, err := conn.ReadMsgUnix(nil, oob)
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 0)
+		msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+		So(err, ShouldBeNil)
+		So(msgs, ShouldHaveLength, 1)
+		fds, err := syscall.ParseUnixRights(&msgs[0])
+		So(err, ShouldBeNil)
+		So(fds, ShouldHaveLength, 1)
+
+		f := os.NewFile(uintptr(fds[0]), "entry")
+		defer f.Close()
+		_, err = f.Seek(0, 0)
+		So(err, ShouldBeNil)
+		p, err := ioutil.ReadAll(f)
+		So(err, ShouldBeNil)
+		fields, err := parseJournalEntry(p)
+		So(err, ShouldBeNil)
+		So(fields["PRIORITY"], ShouldEqual, "3")
+		So(fields["MESSAGE"], ShouldEqual, huge)
+
+		Convey("even without memfd", func() {
+			shm, err := shmFile([]byte("MESSAGE=hi\n"))
+			if err != nil {
+				SkipSo(err, ShouldBeNil) // no /dev/shm
+				return
+			}
+			defer shm.Close()
+			_, err = shm.Seek(0, 0)
+			So(err, ShouldBeNil)
+			p, err := ioutil.ReadAll(shm)
+			So(err, ShouldBeNil)
+			So(string(p), ShouldEqual, "MESSAGE=hi\n")
+		})
+	})
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package logging
+
+import (
+	"errors"
+	"net"
+)
+
+// sendJournalFile fails: passing entries in files needs memfds or /dev/shm,
+// and journald only runs on Linux anyway.
+func sendJournalFile(conn *net.UnixConn, p []byte) error {
+	return errors.New("JournalWriter: entry too large for a datagram")
+}
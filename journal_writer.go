@@ -0,0 +1,213 @@
+package logging
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// DefaultJournalSocket is the socket on which systemd-journald accepts entries
+// in its native protocol.
+const DefaultJournalSocket = "/run/systemd/journal/socket"
+
+// JournalWriter is a Writer that sends entries to systemd-journald using its
+// native protocol, so that they can be filtered by their metadata:
+//
+//	journalctl SYSLOG_IDENTIFIER=app CONTEXT=Flow=f1 PRIORITY=4
+//
+// Each entry is sent with the following fields:
+//
+//	MESSAGE            the rendered message
+//	PRIORITY           the syslog severity, as for SyslogWriter
+//	CODE_FILE          the file, line and function that logged it, if known
+//	CODE_LINE
+//	CODE_FUNC
+//	SYSLOG_IDENTIFIER  Identifier
+//	CONTEXT            the entry's context, if any
+//
+// followed by the entry's fields.  Field names are converted to valid journal
+// field names by upper-casing them and replacing other characters than letters,
+// digits and underscores with underscores; leading underscores and digits,
+// which journald reserves, are dropped.  Fields whose names would be those of
+// the fields above, or of other fields that journald interprets, such as
+// MESSAGE_ID or SYSLOG_PID, are prefixed with FIELD_, so that "message"
+// becomes FIELD_MESSAGE.
+//
+// Path is the journald socket and defaults to DefaultJournalSocket.  Identifier
+// defaults to the base name of the program.  Entries too large for a datagram
+// are written to a sealed memfd, or on kernels without memfd to an unlinked
+// file in /dev/shm, whose descriptor is passed to journald instead.  This is
+// only supported on Linux.
+type JournalWriter struct {
+	Path       string
+	Identifier string
+
+	mutex  sync.Mutex
+	conn   *net.UnixConn
+	closed bool
+}
+
+var errJournalWriterClosed = errors.New("JournalWriter is closed")
+
+func (w *JournalWriter) Write(e Entry) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	w.appendEntry(buf, e)
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.closed {
+		return errJournalWriterClosed
+	}
+	err := w.send(*buf)
+	if err != nil {
+		// journald may have restarted.
+		w.disconnect()
+		err = w.send(*buf)
+		if err != nil {
+			w.disconnect()
+		}
+	}
+	return err
+}
+
+// Close closes the connection to journald.  Later writes fail.
+func (w *JournalWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.closed = true
+	return w.disconnect()
+}
+
+// send sends an encoded entry, connecting first if necessary.  w.mutex must be
+// held.
+func (w *JournalWriter) send(p []byte) error {
+	if w.conn == nil {
+		path := w.Path
+		if path == "" {
+			path = DefaultJournalSocket
+		}
+		conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+	}
+	_, err := w.conn.Write(p)
+	if errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS) {
+		return sendJournalFile(w.conn, p)
+	}
+	return err
+}
+
+// disconnect closes the connection, if any.  w.mutex must be held.
+func (w *JournalWriter) disconnect() error {
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// appendEntry appends e to buf in the journal's native format.
+func (w *JournalWriter) appendEntry(buf *buffer, e Entry) {
+	// Render the message after the other fields, in place, so that it need
+	// not be copied to be measured.
+	b := append(*buf, "PRIORITY="...)
+	b = strconv.AppendInt(b, int64(syslogSeverity(e.Level)), 10)
+	b = append(b, '\n')
+	if e.File != "" {
+		b = appendJournalField(b, "CODE_FILE", e.File)
+	}
+	if e.Line != -1 {
+		b = append(b, "CODE_LINE="...)
+		b = strconv.AppendInt(b, int64(e.Line), 10)
+		b = append(b, '\n')
+	}
+	if e.Function != "" {
+		b = appendJournalField(b, "CODE_FUNC", e.Function)
+	}
+	identifier := w.Identifier
+	if identifier == "" {
+		identifier = filepath.Base(os.Args[0])
+	}
+	b = appendJournalField(b, "SYSLOG_IDENTIFIER", identifier)
+	if e.Context != "" {
+		b = appendJournalField(b, "CONTEXT", e.Context)
+	}
+	for _, f := range e.Fields {
+		if name := journalFieldName(f.Key); name != "" {
+			b = appendJournalField(b, name, fieldText(f.Value))
+		}
+	}
+
+	// MESSAGE is always sent in the binary form: the name, a newline, the
+	// value's length as a little-endian uint64, the value and a newline.
+	b = append(b, "MESSAGE\n"...)
+	lenAt := len(b)
+	b = append(b, make([]byte, 8)...)
+	*buf = b
+	appendMessage(buf, e.Fmt, e.Args)
+	binary.LittleEndian.PutUint64((*buf)[lenAt:], uint64(len(*buf)-lenAt-8))
+	*buf = append(*buf, '\n')
+}
+
+// appendJournalField appends a field as NAME=value, or in the binary form if
+// the value contains a newline.
+func appendJournalField(b []byte, name, value string) []byte {
+	b = append(b, name...)
+	if strings.IndexByte(value, '\n') < 0 {
+		b = append(b, '=')
+	} else {
+		b = append(b, '\n')
+		b = binary.LittleEndian.AppendUint64(b, uint64(len(value)))
+	}
+	b = append(b, value...)
+	return append(b, '\n')
+}
+
+// journalReservedNames are the fields sent by JournalWriter itself and the
+// other fields that journald gives a meaning to.
+var journalReservedNames = map[string]bool{
+	"MESSAGE": true, "MESSAGE_ID": true, "PRIORITY": true, "CODE_FILE": true,
+	"CODE_LINE": true, "CODE_FUNC": true, "ERRNO": true, "INVOCATION_ID": true,
+	"USER_INVOCATION_ID": true, "SYSLOG_FACILITY": true, "SYSLOG_IDENTIFIER": true,
+	"SYSLOG_PID": true, "SYSLOG_TIMESTAMP": true, "SYSLOG_RAW": true,
+	"DOCUMENTATION": true, "TID": true, "UNIT": true, "USER_UNIT": true,
+	"CONTEXT": true,
+}
+
+// journalFieldName converts key to a valid journal field name, or returns ""
+// if it has no letters.
+func journalFieldName(key string) string {
+	// Trim after mapping, so that runes mapped to underscores cannot start
+	// the name either.
+	name := strings.TrimLeft(strings.Map(journalNameRune, key), "_0123456789")
+	if name == "" {
+		return ""
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	if journalReservedNames[name] {
+		name = "FIELD_" + name
+	}
+	return name
+}
+
+func journalNameRune(r rune) rune {
+	switch {
+	case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+		return r
+	case r >= 'a' && r <= 'z':
+		return r - 'a' + 'A'
+	}
+	return '_'
+}
@@ -0,0 +1,139 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// parseJournalEntry decodes an entry in the journal's native format.
+func parseJournalEntry(p []byte) (map[string]string, error) {
+	fields := map[string]string{}
+	for len(p) > 0 {
+		i := bytes.IndexAny(p, "=\n")
+		if i < 0 {
+			return nil, fmt.Errorf("truncated field %q", p)
+		}
+		name := string(p[:i])
+		if p[i] == '=' {
+			end := bytes.IndexByte(p, '\n')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated field %s", name)
+			}
+			fields[name] = string(p[i+1 : end])
+			p = p[end+1:]
+			continue
+		}
+		p = p[i+1:]
+		if len(p) < 8 {
+			return nil, fmt.Errorf("truncated length of field %s", name)
+		}
+		n := binary.LittleEndian.Uint64(p)
+		p = p[8:]
+		if uint64(len(p)) < n+1 || p[n] != '\n' {
+			return nil, fmt.Errorf("bad length %d of field %s", n, name)
+		}
+		fields[name] = string(p[:n])
+		p = p[n+1:]
+	}
+	return fields, nil
+}
+
+// listenJournal listens on a journal socket in dir.
+func listenJournal(dir string) (*net.UnixConn, string) {
+	path := filepath.Join(dir, "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	So(err, ShouldBeNil)
+	return conn, path
+}
+
+func TestJournalWriter(t *testing.T) {
+	Convey("JournalWriter", t, func() {
+		dir, err := ioutil.TempDir("", "journal_writer_test")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		conn, path := listenJournal(dir)
+		defer conn.Close()
+
+		w := &JournalWriter{Path: path, Identifier: "app"}
+		defer w.Close()
+		read := func() map[string]string {
+			buf := make([]byte, 4096)
+			n, err := conn.Read(buf)
+			So(err, ShouldBeNil)
+			fields, err := parseJournalEntry(buf[:n])
+			So(err, ShouldBeNil)
+			return fields
+		}
+
+		Convey("should send the entry's metadata and fields", func() {
+			So(w.Write(Entry{
+				Level:    WarnLevel,
+				Time:     time.Now(),
+				File:     "/src/flow/block.go",
+				Line:     12,
+				Function: "flow.(*Block).Add",
+				Context:  "Flow=f1",
+				Fmt:      "Creating %d blocks",
+				Args:     []interface{}{20},
+				Fields:   Fields{{"block", "addFoo"}, {"_2nd-try", true}, {"note", "two\nlines"}, {"_", 1}, {"-foo", 2}, {"名前", 3}},
+			}), ShouldBeNil)
+			So(read(), ShouldResemble, map[string]string{
+				"MESSAGE":           "Creating 20 blocks",
+				"PRIORITY":          "4",
+				"CODE_FILE":         "/src/flow/block.go",
+				"CODE_LINE":         "12",
+				"CODE_FUNC":         "flow.(*Block).Add",
+				"SYSLOG_IDENTIFIER": "app",
+				"CONTEXT":           "Flow=f1",
+				"BLOCK":             "addFoo",
+				"ND_TRY":            "true",
+				"NOTE":              "two\nlines",
+				"FOO":               "2",
+			})
+		})
+		Convey("should not let fields replace the entry's metadata", func() {
+			log := &StdLogger{Writer: w, MinLevel: TraceLevel, NoCaller: true}
+			log.With("message", "m", "Priority", 1, "context", "c", "syslog_pid", 2).Info("hi")
+			So(read(), ShouldResemble, map[string]string{
+				"MESSAGE":           "hi",
+				"PRIORITY":          "6",
+				"SYSLOG_IDENTIFIER": "app",
+				"FIELD_MESSAGE":     "m",
+				"FIELD_PRIORITY":    "1",
+				"FIELD_CONTEXT":     "c",
+				"FIELD_SYSLOG_PID":  "2",
+			})
+		})
+		Convey("should omit unknown metadata", func() {
+			log := &StdLogger{Writer: w, MinLevel: TraceLevel, NoCaller: true}
+			log.Debug("multi\nline")
+			So(read(), ShouldResemble, map[string]string{
+				"MESSAGE":           "multi\nline",
+				"PRIORITY":          "7",
+				"SYSLOG_IDENTIFIER": "app",
+			})
+		})
+		Convey("should reconnect after journald restarts", func() {
+			So(w.Write(Entry{Level: InfoLevel, Line: -1, Args: []interface{}{"one"}}), ShouldBeNil)
+			So(read()["MESSAGE"], ShouldEqual, "one")
+			conn.Close()
+			os.Remove(path)
+			conn, _ = listenJournal(dir)
+			So(w.Write(Entry{Level: InfoLevel, Line: -1, Args: []interface{}{"two"}}), ShouldBeNil)
+			So(read()["MESSAGE"], ShouldEqual, "two")
+		})
+		Convey("should fail once closed", func() {
+			So(w.Close(), ShouldBeNil)
+			So(w.Write(Entry{Level: InfoLevel, Line: -1}), ShouldEqual, errJournalWriterClosed)
+		})
+	})
+}
@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// JSONWriter writes each entry as a single-line JSON object followed by a
+// newline (JSON Lines).  An entry is rendered as:
+//
+//	{"level":"info","time":"2016-05-23T21:21:18.901Z","file":"/path/to/file.go",
+//	 "line":12,"function":"main.main","context":"Flow=f1","msg":"Creating 20 blocks","args":[20],
+//	 "fields":{"block":"addFoo"}}
+//
+// where msg is the fully rendered message and args holds the raw arguments.
+// Arguments that cannot be encoded as JSON are included as their fmt.Sprint
+// representation, and errors as their Error() string.  file, line and function
+// are omitted when unknown, args and fields when empty.
+type JSONWriter struct {
+	Writer io.Writer
+	mutex  sync.Mutex
+}
+
+type jsonEntry struct {
+	Level   string                     `json:"level"`
+	Time    string                     `json:"time"`
+	File    string                     `json:"file,omitempty"`
+	Line    *int                       `json:"line,omitempty"`
+	Func    string                     `json:"function,omitempty"`
+	Context string                     `json:"context"`
+	Msg     string                     `json:"msg"`
+	Args    []json.RawMessage          `json:"args,omitempty"`
+	Fields  map[string]json.RawMessage `json:"fields,omitempty"`
+}
+
+func (j *JSONWriter) Write(e Entry) error {
+	je := jsonEntry{
+		Level:   e.Level.Name(),
+		Time:    e.Time.Format(time.RFC3339Nano),
+		File:    e.File,
+		Func:    e.Function,
+		Context: e.Context,
+		Msg:     e.Message(),
+	}
+	if e.Line != -1 {
+		je.Line = &e.Line
+	}
+	for _, arg := range e.Args {
+		je.Args = append(je.Args, jsonValue(arg))
+	}
+	if len(e.Fields) > 0 {
+		je.Fields = make(map[string]json.RawMessage, len(e.Fields))
+		for _, f := range e.Fields {
+			je.Fields[f.Key] = jsonValue(f.Value)
+		}
+	}
+
+	// Encode in memory first so that concurrent entries are never interleaved.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(je); err != nil {
+		return err
+	}
+
+	j.mutex.Lock()
+	_, err := buf.WriteTo(j.Writer)
+	j.mutex.Unlock()
+
+	return err
+}
+
+// jsonValue encodes v as JSON, falling back to its string representation if v
+// is not serializable.
+func jsonValue(v interface{}) json.RawMessage {
+	if err, ok := v.(error); ok {
+		v = err.Error()
+	}
+	if b, err := json.Marshal(v); err == nil {
+		return b
+	}
+	b, _ := json.Marshal(fmt.Sprint(v))
+	return b
+}
@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	var w = JSONWriter{Writer: &buf}
+
+	var ts = time.Date(2016, 5, 23, 21, 21, 18, 901000000, time.UTC)
+
+	decode := func() map[string]interface{} {
+		var m map[string]interface{}
+		So(json.Unmarshal(buf.Bytes(), &m), ShouldBeNil)
+		return m
+	}
+
+	Convey("JSONWriter", t, func() {
+		buf.Reset()
+		Convey("should format entries as a single JSON line", func() {
+			w.Write(Entry{Level: InfoLevel, Time: ts, File: "/path/to/file.go", Line: 12, Context: "ctx",
+				Fmt: "(%s %d)", Args: args("Hi", 4)})
+			So(buf.String(), ShouldEqual, `{"level":"info","time":"2016-05-23T21:21:18.901Z","file":"/path/to/file.go",`+
+				`"line":12,"context":"ctx","msg":"(Hi 4)","args":["Hi",4]}`+"\n")
+		})
+		Convey("should keep multi-line messages on one line", func() {
+			w.Write(Entry{Level: ErrorLevel, Fmt: "a\nb\nc"})
+			So(strings.Count(buf.String(), "\n"), ShouldEqual, 1)
+			So(decode()["msg"], ShouldEqual, "a\nb\nc")
+		})
+		Convey("should render messages without a format like TextWriter", func() {
+			w.Write(Entry{Level: DebugLevel, Fmt: kNO_FORMAT, Args: args("Hi", "there")})
+			So(decode()["msg"], ShouldEqual, "Hi there")
+		})
+		Convey("should omit unknown origins", func() {
+			w.Write(Entry{Level: InfoLevel, Line: -1})
+			m := decode()
+			So(m, ShouldNotContainKey, "file")
+			So(m, ShouldNotContainKey, "line")
+		})
+		Convey("should fall back to strings for unserializable args", func() {
+			w.Write(Entry{Level: InfoLevel, Fmt: kNO_FORMAT, Args: args(errors.New("boom"), func() {}, 1.5)})
+			a := decode()["args"].([]interface{})
+			So(a[0], ShouldEqual, "boom")
+			So(a[1], ShouldStartWith, "0x")
+			So(a[2], ShouldEqual, 1.5)
+		})
+		Convey("should not panic on invalid levels", func() {
+			So(func() { w.Write(Entry{}) }, ShouldNotPanic)
+			So(decode()["level"], ShouldEqual, "Level(0)")
+		})
+		Convey("should include fields", func() {
+			w.Write(Entry{Level: InfoLevel, Fields: Fields{{"flow", "f1"}, {"n", 3}}})
+			So(decode()["fields"], ShouldResemble, map[string]interface{}{"flow": "f1", "n": 3.0})
+		})
+	})
+}
+
+func TestNewJSONLogger(t *testing.T) {
+	Convey("NewJSONLogger should write JSON to the provided io.Writer", t, func() {
+		var buf bytes.Buffer
+		log := NewJSONLogger(&buf, "Flow=f1", DebugLevel)
+		log.Trace("xxx")
+		log.Infof("Creating %d blocks", 20)
+		So(buf.String(), ShouldNotContainSubstring, "xxx")
+		So(buf.String(), ShouldContainSubstring, `"context":"Flow=f1","msg":"Creating 20 blocks"`)
+		So(buf.String(), ShouldContainSubstring, "json_writer_test.go")
+	})
+}
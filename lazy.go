@@ -0,0 +1,61 @@
+package logging
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Lazy is a log argument whose value is computed only if the entry is logged,
+// for arguments that are expensive to produce:
+//
+//	log.Tracef("Graph: %s", logging.Lazy(func() interface{} { return dumpGraph() }))
+//
+// StdLogger calls the function once per logged entry, after checking the
+// level, and passes the result on to its Writer.  Loggers that format
+// arguments themselves call it when formatting.  Use Enabled instead to guard
+// code that is expensive for other reasons.
+type Lazy func() interface{}
+
+// Format formats the function's result as if it had been passed directly.
+func (f Lazy) Format(s fmt.State, verb rune) {
+	fmt.Fprintf(s, formatDirective(s, verb), f())
+}
+
+// formatDirective reconstructs the directive, such as "%-8.3f", that produced
+// the given state and verb.
+func formatDirective(s fmt.State, verb rune) string {
+	directive := []byte{'%'}
+	for _, flag := range "+-# 0" {
+		if s.Flag(int(flag)) {
+			directive = append(directive, byte(flag))
+		}
+	}
+	if width, ok := s.Width(); ok {
+		directive = strconv.AppendInt(directive, int64(width), 10)
+	}
+	if prec, ok := s.Precision(); ok {
+		directive = append(directive, '.')
+		directive = strconv.AppendInt(directive, int64(prec), 10)
+	}
+	return string(append(directive, string(verb)...))
+}
+
+// resolveLazy returns vals with any Lazy arguments replaced by their values.
+// vals itself is returned if it holds none.
+func resolveLazy(vals []interface{}) []interface{} {
+	for i, v := range vals {
+		if _, ok := v.(Lazy); ok {
+			resolved := make([]interface{}, len(vals))
+			copy(resolved, vals[:i])
+			for j := i; j < len(vals); j++ {
+				if f, ok := vals[j].(Lazy); ok {
+					resolved[j] = f()
+				} else {
+					resolved[j] = vals[j]
+				}
+			}
+			return resolved
+		}
+	}
+	return vals
+}
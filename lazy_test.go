@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLazy(t *testing.T) {
+	Convey("Lazy arguments", t, func() {
+		calls := 0
+		expensive := Lazy(func() interface{} { calls++; return 3.14159 })
+
+		Convey("should format like their value", func() {
+			So(fmt.Sprintf("%v|%6.2f|%-8.1e|", expensive, expensive, expensive), ShouldEqual,
+				fmt.Sprintf("%v|%6.2f|%-8.1e|", 3.14159, 3.14159, 3.14159))
+			So(fmt.Sprint("pi", expensive), ShouldEqual, "pi3.14159")
+		})
+		Convey("should be evaluated once by a StdLogger, and only if enabled", func() {
+			var c captureWriter
+			log := &StdLogger{Writer: &c, MinLevel: DebugLevel}
+			log.Tracef("%.2f", expensive)
+			So(calls, ShouldEqual, 0)
+			log.Debugf("%.2f", expensive)
+			So(calls, ShouldEqual, 1)
+			So(c.Args, ShouldResemble, []interface{}{3.14159})
+			So(Entry(c).Message(), ShouldEqual, "3.14")
+		})
+		Convey("should be evaluated once by a TeeLogger", func() {
+			var buf1, buf2 bytes.Buffer
+			tee := NewTeeLogger(NewTextLogger(&buf1, "", InfoLevel), NewTextLogger(&buf2, "", WarnLevel))
+			tee.Debug(expensive)
+			So(calls, ShouldEqual, 0)
+			tee.Info(expensive)
+			So(calls, ShouldEqual, 1)
+			So(buf1.String(), ShouldEndWith, ": 3.14159\n")
+			So(buf2.String(), ShouldBeEmpty)
+		})
+		Convey("should not be evaluated by a cancelled logger", func() {
+			cl := &CancellableLogger{Logger: NewMemLogger()}
+			cl.Cancel()
+			cl.Errorf("%v", expensive)
+			So(calls, ShouldEqual, 0)
+		})
+	})
+}
+
+func TestEnabled(t *testing.T) {
+	Convey("Enabled", t, func() {
+		std := &StdLogger{Writer: &captureWriter{}, MinLevel: InfoLevel}
+		So(std.Enabled(DebugLevel), ShouldBeFalse)
+		So(std.Enabled(InfoLevel), ShouldBeTrue)
+
+		Convey("should honour VModule", func() {
+			std.VModule = ParseVModuleOrDie("lazy_test=trace")
+			So(std.Enabled(TraceLevel), ShouldBeTrue)
+			std.VModule = ParseVModuleOrDie("lazy_test=error,other=trace")
+			So(std.Enabled(WarnLevel), ShouldBeFalse)
+			So(std.Enabled(TraceLevel), ShouldBeFalse)
+		})
+		Convey("should check all loggers of a TeeLogger", func() {
+			tee := NewTeeLogger(std, &StdLogger{Writer: &captureWriter{}, MinLevel: WarnLevel})
+			So(tee.Enabled(InfoLevel), ShouldBeTrue)
+			So(tee.Enabled(DebugLevel), ShouldBeFalse)
+		})
+		Convey("should be false once cancelled", func() {
+			cl := &CancellableLogger{Logger: std}
+			derived := cl.With("k", "v")
+			So(derived.Enabled(ErrorLevel), ShouldBeTrue)
+			cl.Cancel()
+			So(cl.Enabled(ErrorLevel), ShouldBeFalse)
+			So(derived.Enabled(ErrorLevel), ShouldBeFalse)
+		})
+		Convey("should use System at package level", func() {
+			var saved Logger
+			System, saved = &StdLogger{Writer: &captureWriter{}, MinLevel: WarnLevel}, System
+			defer func() { System = saved }()
+			So(Enabled(InfoLevel), ShouldBeFalse)
+			System.(*StdLogger).VModule = ParseVModuleOrDie("lazy_test=info")
+			So(Enabled(InfoLevel), ShouldBeTrue)
+		})
+		Convey("should cover other loggers", func() {
+			So(DiscardLogger{}.Enabled(FatalLevel), ShouldBeFalse)
+			mem := NewMemLogger()
+			mem.SetLogLevel(WarnLevel)
+			So(mem.Enabled(InfoLevel), ShouldBeFalse)
+			So(mem.Enabled(WarnLevel), ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,297 @@
+package logging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LevelSet is a collection of named loggers whose levels can be inspected and
+// changed at runtime, for example through a LevelHandler.
+type LevelSet interface {
+	// Levels returns the current level of each logger, by name.
+	Levels() map[string]Level
+	// SetLevel changes the level of the named logger, returning
+	// ErrNoSuchLogger if there is none.
+	SetLevel(name string, level Level) error
+	// HasLevel reports whether the named logger has a level of its own rather
+	// than one inherited from another logger.
+	HasLevel(name string) bool
+	// ClearLevel makes the named logger inherit its level again.  Sets whose
+	// loggers always have a level of their own ignore it.
+	ClearLevel(name string)
+}
+
+// ErrNoSuchLogger is returned by LevelSet.SetLevel for an unknown name.
+var ErrNoSuchLogger = errors.New("No such logger")
+
+// LoggerSet is a LevelSet of explicitly registered loggers.  The zero value is
+// empty and ready to use.
+type LoggerSet struct {
+	mutex   sync.RWMutex
+	loggers map[string]Logger
+}
+
+// Register adds l to the set under name, replacing any logger already
+// registered with that name.
+func (s *LoggerSet) Register(name string, l Logger) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.loggers == nil {
+		s.loggers = map[string]Logger{}
+	}
+	s.loggers[name] = l
+}
+
+// Unregister removes the named logger from the set.
+func (s *LoggerSet) Unregister(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.loggers, name)
+}
+
+func (s *LoggerSet) Levels() map[string]Level {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	levels := make(map[string]Level, len(s.loggers))
+	for name, l := range s.loggers {
+		levels[name] = l.LogLevel()
+	}
+	return levels
+}
+
+func (s *LoggerSet) SetLevel(name string, level Level) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	l, ok := s.loggers[name]
+	if !ok {
+		return ErrNoSuchLogger
+	}
+	l.SetLogLevel(level)
+	return nil
+}
+
+// HasLevel reports whether the named logger is registered, since registered
+// loggers always have a level of their own.
+func (s *LoggerSet) HasLevel(name string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	_, ok := s.loggers[name]
+	return ok
+}
+
+// ClearLevel does nothing, since registered loggers do not inherit levels.
+func (s *LoggerSet) ClearLevel(name string) {}
+
+// LevelHandler is an http.Handler for inspecting and changing the levels of a
+// LevelSet in a running server.  The request path, with any mount prefix
+// removed by http.StripPrefix, names the logger:
+//
+//	GET /           lists all loggers and their levels
+//	GET /name       shows the named logger
+//	PUT /name       sets the named logger's level
+//
+// PUT accepts either a plain text body holding the level, such as "debug", or
+// a JSON body of the form {"level": "debug", "revert_after": "10m"}.  The level
+// and revert_after may also be given as query parameters.  If revert_after is
+// set, the logger returns to its previous level once that duration has passed,
+// unless it is changed again in the meantime.  A logger whose level was
+// inherited, such as one in a Registry, goes back to inheriting it.
+//
+// Responses are plain text, one "name level" line per logger, unless the
+// request accepts application/json or has format=json in its query.
+type LevelHandler struct {
+	levels  LevelSet
+	mutex   sync.Mutex
+	reverts map[string]*levelRevert
+}
+
+// levelRevert is a pending automatic revert of a logger's level.
+type levelRevert struct {
+	level Level
+	// inherit is set if the logger had no level of its own before the change.
+	inherit bool
+	at      time.Time
+	timer   *time.Timer
+}
+
+// NewLevelHandler returns a LevelHandler that controls the loggers in levels.
+func NewLevelHandler(levels LevelSet) *LevelHandler {
+	return &LevelHandler{levels: levels, reverts: map[string]*levelRevert{}}
+}
+
+// levelStatus is the JSON form of a logger's level in LevelHandler responses.
+type levelStatus struct {
+	Level    string     `json:"level"`
+	RevertTo string     `json:"revert_to,omitempty"`
+	RevertAt *time.Time `json:"revert_at,omitempty"`
+}
+
+// levelRequest is the JSON form of a PUT request to a LevelHandler.
+type levelRequest struct {
+	Level       string `json:"level"`
+	RevertAfter string `json:"revert_after"`
+}
+
+// maxLevelRequestSize limits the size of PUT bodies read by LevelHandler.
+const maxLevelRequestSize = 4096
+
+func (h *LevelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(r.URL.Path, "/")
+	switch r.Method {
+	case "GET", "HEAD":
+	case "PUT", "POST":
+		if name == "" {
+			http.Error(w, "No logger specified", http.StatusBadRequest)
+			return
+		}
+		level, revertAfter, err := parseLevelRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := h.setLevel(name, level, revertAfter); err == ErrNoSuchLogger {
+			http.Error(w, fmt.Sprintf("%v: %q", err, name), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	statuses := h.statuses()
+	if name != "" {
+		status, ok := statuses[name]
+		if !ok {
+			http.Error(w, fmt.Sprintf("%v: %q", ErrNoSuchLogger, name), http.StatusNotFound)
+			return
+		}
+		statuses = map[string]levelStatus{name: status}
+	}
+	writeLevelStatuses(w, r, statuses)
+}
+
+// setLevel sets the level of the named logger, scheduling a revert to the
+// previous level after revertAfter if it is positive.
+func (h *LevelHandler) setLevel(name string, level Level, revertAfter time.Duration) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	previous, ok := h.levels.Levels()[name]
+	if !ok {
+		return ErrNoSuchLogger
+	}
+	inherit := !h.levels.HasLevel(name)
+	if rv := h.reverts[name]; rv != nil {
+		// Keep reverting to the level from before the first temporary change.
+		rv.timer.Stop()
+		previous, inherit = rv.level, rv.inherit
+		delete(h.reverts, name)
+	}
+	if err := h.levels.SetLevel(name, level); err != nil {
+		return err
+	}
+	if revertAfter > 0 {
+		rv := &levelRevert{level: previous, inherit: inherit, at: time.Now().Add(revertAfter)}
+		rv.timer = time.AfterFunc(revertAfter, func() { h.revert(name, rv) })
+		h.reverts[name] = rv
+	}
+	return nil
+}
+
+func (h *LevelHandler) revert(name string, rv *levelRevert) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.reverts[name] != rv {
+		return // superseded by a later change
+	}
+	delete(h.reverts, name)
+	if rv.inherit {
+		h.levels.ClearLevel(name)
+		return
+	}
+	if err := h.levels.SetLevel(name, rv.level); err != nil && err != ErrNoSuchLogger {
+		Errorf("Failed to revert level of logger %q: %v", name, err)
+	}
+}
+
+func (h *LevelHandler) statuses() map[string]levelStatus {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	levels := h.levels.Levels()
+	statuses := make(map[string]levelStatus, len(levels))
+	for name, level := range levels {
+		status := levelStatus{Level: level.Name()}
+		if rv := h.reverts[name]; rv != nil {
+			at := rv.at
+			status.RevertTo, status.RevertAt = rv.level.Name(), &at
+		}
+		statuses[name] = status
+	}
+	return statuses
+}
+
+func parseLevelRequest(r *http.Request) (Level, time.Duration, error) {
+	req := levelRequest{
+		Level:       r.URL.Query().Get("level"),
+		RevertAfter: r.URL.Query().Get("revert_after"),
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxLevelRequestSize))
+	if err != nil {
+		return 0, 0, err
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+		if err := json.Unmarshal(body, &req); err != nil {
+			return 0, 0, fmt.Errorf("Invalid JSON request: %v", err)
+		}
+	} else if text := strings.TrimSpace(string(body)); text != "" {
+		req.Level = text
+	}
+
+	level, err := ParseLevel(req.Level)
+	if err != nil {
+		return 0, 0, err
+	}
+	var revertAfter time.Duration
+	if req.RevertAfter != "" {
+		if revertAfter, err = time.ParseDuration(req.RevertAfter); err != nil {
+			return 0, 0, fmt.Errorf("Invalid revert_after: %v", err)
+		}
+	}
+	return level, revertAfter, nil
+}
+
+func writeLevelStatuses(w http.ResponseWriter, r *http.Request, statuses map[string]levelStatus) {
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(statuses)
+		return
+	}
+
+	names := make([]string, 0, len(statuses))
+	for name := range statuses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, name := range names {
+		status := statuses[name]
+		if status.RevertAt != nil {
+			fmt.Fprintf(w, "%s %s (reverts to %s at %s)\n", name, status.Level,
+				status.RevertTo, status.RevertAt.Format(time.RFC3339))
+		} else {
+			fmt.Fprintf(w, "%s %s\n", name, status.Level)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func serveLevels(h http.Handler, method, target, contentType, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestLoggerSet(t *testing.T) {
+	Convey("LoggerSet", t, func() {
+		var s LoggerSet
+		a := NewMemLogger()
+		s.Register("a", a)
+		So(s.Levels(), ShouldResemble, map[string]Level{"a": TraceLevel})
+		So(s.SetLevel("a", WarnLevel), ShouldBeNil)
+		So(a.LogLevel(), ShouldEqual, WarnLevel)
+		So(s.SetLevel("b", WarnLevel), ShouldEqual, ErrNoSuchLogger)
+		So(s.HasLevel("a"), ShouldBeTrue)
+		So(s.HasLevel("b"), ShouldBeFalse)
+		s.Register("tee", NewTeeLogger(NewMemLogger(), &StdLogger{Writer: &captureWriter{}, MinLevel: InfoLevel}))
+		So(s.Levels(), ShouldResemble, map[string]Level{"a": WarnLevel, "tee": TraceLevel})
+		s.Unregister("tee")
+		s.Unregister("a")
+		So(s.Levels(), ShouldBeEmpty)
+	})
+}
+
+func TestLevelHandler(t *testing.T) {
+	Convey("LevelHandler", t, func() {
+		var s LoggerSet
+		flow := &StdLogger{Writer: &captureWriter{}, MinLevel: InfoLevel}
+		s.Register("flow", flow)
+		s.Register("http/server", &StdLogger{Writer: &captureWriter{}, MinLevel: WarnLevel})
+		h := NewLevelHandler(&s)
+
+		Convey("should list loggers as text", func() {
+			w := serveLevels(h, "GET", "/", "", "")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.String(), ShouldEqual, "flow info\nhttp/server warn\n")
+		})
+		Convey("should list loggers as JSON", func() {
+			w := serveLevels(h, "GET", "/?format=json", "", "")
+			So(w.Header().Get("Content-Type"), ShouldEqual, "application/json")
+			var got map[string]levelStatus
+			So(json.Unmarshal(w.Body.Bytes(), &got), ShouldBeNil)
+			So(got, ShouldResemble, map[string]levelStatus{
+				"flow":        {Level: "info"},
+				"http/server": {Level: "warn"},
+			})
+		})
+		Convey("should show a single logger", func() {
+			w := serveLevels(h, "GET", "/http/server", "", "")
+			So(w.Body.String(), ShouldEqual, "http/server warn\n")
+			So(serveLevels(h, "GET", "/nope", "", "").Code, ShouldEqual, http.StatusNotFound)
+		})
+		Convey("should list loggers with unset levels", func() {
+			s.Register("zero", &StdLogger{Writer: &captureWriter{}})
+			w := serveLevels(h, "GET", "/", "", "")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.String(), ShouldEqual, "flow info\nhttp/server warn\nzero Level(0)\n")
+			w = serveLevels(h, "GET", "/zero?format=json", "", "")
+			So(w.Body.String(), ShouldContainSubstring, `"Level(0)"`)
+		})
+		Convey("should set levels from text, query and JSON", func() {
+			w := serveLevels(h, "PUT", "/flow", "text/plain", "debug\n")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.String(), ShouldEqual, "flow debug\n")
+			So(flow.LogLevel(), ShouldEqual, DebugLevel)
+
+			serveLevels(h, "PUT", "/flow?level=trace", "", "")
+			So(flow.LogLevel(), ShouldEqual, TraceLevel)
+
+			serveLevels(h, "PUT", "/flow", "application/json", `{"level": "error"}`)
+			So(flow.LogLevel(), ShouldEqual, ErrorLevel)
+		})
+		Convey("should reject bad requests", func() {
+			So(serveLevels(h, "PUT", "/flow", "", "loud").Code, ShouldEqual, http.StatusBadRequest)
+			So(serveLevels(h, "PUT", "/flow", "application/json", "{").Code, ShouldEqual, http.StatusBadRequest)
+			So(serveLevels(h, "PUT", "/flow?revert_after=soon", "", "debug").Code, ShouldEqual, http.StatusBadRequest)
+			So(serveLevels(h, "PUT", "/", "", "debug").Code, ShouldEqual, http.StatusBadRequest)
+			So(serveLevels(h, "PUT", "/nope", "", "debug").Code, ShouldEqual, http.StatusNotFound)
+			So(serveLevels(h, "DELETE", "/flow", "", "").Code, ShouldEqual, http.StatusMethodNotAllowed)
+			So(flow.LogLevel(), ShouldEqual, InfoLevel)
+		})
+		Convey("should revert temporary changes", func() {
+			w := serveLevels(h, "PUT", "/flow", "application/json", `{"level": "trace", "revert_after": "1h"}`)
+			So(w.Body.String(), ShouldStartWith, "flow trace (reverts to info at ")
+			// A second temporary change still reverts to the original level.
+			serveLevels(h, "PUT", "/flow?revert_after=20ms", "", "debug")
+			So(flow.LogLevel(), ShouldEqual, DebugLevel)
+			for i := 0; i < 100 && flow.LogLevel() != InfoLevel; i++ {
+				time.Sleep(10 * time.Millisecond)
+			}
+			So(flow.LogLevel(), ShouldEqual, InfoLevel)
+			So(serveLevels(h, "GET", "/flow", "", "").Body.String(), ShouldEqual, "flow info\n")
+		})
+		Convey("should cancel a revert on a permanent change", func() {
+			serveLevels(h, "PUT", "/flow?revert_after=20ms", "", "debug")
+			serveLevels(h, "PUT", "/flow", "", "warn")
+			time.Sleep(50 * time.Millisecond)
+			So(flow.LogLevel(), ShouldEqual, WarnLevel)
+		})
+	})
+}
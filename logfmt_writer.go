@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"io"
+	"sync"
+)
+
+// logfmtTimestampFormat is the time layout of LogfmtWriter timestamps.
+const logfmtTimestampFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// LogfmtWriter writes each entry as a single line of logfmt key=value pairs:
+//
+//	level=info ts=2016-05-23T21:21:18.901Z caller=block.go:12 context="Flow=f1" msg="Creating 20 blocks" block=addFoo
+//
+// followed by the entry's fields.  Values are quoted as in Go source if they
+// are empty or contain spaces, quotes, '=' or non-printable characters, so a
+// multi-line message stays on one line with its newlines written as \n.
+// caller and context are omitted when unknown or empty.  LogfmtReader parses
+// the output back into entries.
+type LogfmtWriter struct {
+	Writer io.Writer
+	mutex  sync.Mutex
+}
+
+func (l *LogfmtWriter) Write(e Entry) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	msg := getBuffer()
+	defer putBuffer(msg)
+
+	b := append(*buf, "level="...)
+	b = appendFieldValue(b, e.Level.Name())
+	b = append(b, " ts="...)
+	b = e.Time.AppendFormat(b, logfmtTimestampFormat)
+	if e.File != "" {
+		*msg = appendOrigin(*msg, e.File, e.Line)
+		b = append(b, " caller="...)
+		b = appendFieldValue(b, string(*msg))
+		*msg = (*msg)[:0]
+	}
+	if e.Context != "" {
+		b = append(b, " context="...)
+		b = appendFieldValue(b, e.Context)
+	}
+	b = append(b, " msg="...)
+	appendMessage(msg, e.Fmt, e.Args)
+	b = appendFieldValue(b, string(*msg))
+	if len(e.Fields) > 0 {
+		b = append(b, ' ')
+		b = e.Fields.appendTo(b)
+	}
+	*buf = append(b, '\n')
+
+	l.mutex.Lock()
+	_, err := l.Writer.Write(*buf)
+	l.mutex.Unlock()
+
+	return err
+}
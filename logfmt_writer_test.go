@@ -0,0 +1,54 @@
+package logging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLogfmtWriter(t *testing.T) {
+	var buf bytes.Buffer
+	var w = LogfmtWriter{Writer: &buf}
+
+	var ts = time.Date(2016, 5, 23, 21, 21, 18, 901000000, time.UTC)
+
+	Convey("LogfmtWriter", t, func() {
+		buf.Reset()
+		Convey("should format entries as a single logfmt line", func() {
+			w.Write(Entry{Level: InfoLevel, Time: ts, File: "/path/to/block.go", Line: 12, Context: "Flow=f1",
+				Fmt: "Creating %d blocks", Args: args(20), Fields: Fields{{"block", "addFoo"}, {"n", 3}}})
+			So(buf.String(), ShouldEqual,
+				`level=info ts=2016-05-23T21:21:18.901Z caller=block.go:12 context="Flow=f1" msg="Creating 20 blocks" block=addFoo n=3`+"\n")
+		})
+		Convey("should quote multi-line messages", func() {
+			w.Write(Entry{Level: ErrorLevel, Time: ts, Line: -1, Fmt: "a\nb \"c\""})
+			So(buf.String(), ShouldEqual, `level=error ts=2016-05-23T21:21:18.901Z msg="a\nb \"c\""`+"\n")
+		})
+		Convey("should write empty messages and bare words", func() {
+			w.Write(Entry{Level: DebugLevel, Time: ts, File: "main.go", Line: -1, Fmt: kNO_FORMAT, Args: args("done")})
+			w.Write(Entry{Level: DebugLevel, Time: ts, Line: -1, Fmt: kNO_FORMAT})
+			So(buf.String(), ShouldEqual, "level=debug ts=2016-05-23T21:21:18.901Z caller=main.go msg=done\n"+
+				`level=debug ts=2016-05-23T21:21:18.901Z msg=""`+"\n")
+		})
+		Convey("should not panic on invalid levels", func() {
+			So(func() { w.Write(Entry{Time: ts, Line: -1}) }, ShouldNotPanic)
+			So(buf.String(), ShouldEqual, `level=Level(0) ts=2016-05-23T21:21:18.901Z msg=""`+"\n")
+		})
+		Convey("should be read back by LogfmtReader", func() {
+			log := NewLogfmtLogger(&buf, "Flow=f1", TraceLevel).With("block", "add Foo", "msg", "shadowed")
+			log.Warnf("Computing %d things\n  indented", 5)
+			e, err := NewLogfmtReader(strings.NewReader(buf.String())).Next()
+			So(err, ShouldBeNil)
+			So(e.Level, ShouldEqual, WarnLevel)
+			So(e.File, ShouldEqual, "logfmt_writer_test.go")
+			So(e.Line, ShouldBeGreaterThan, 0)
+			So(e.Context, ShouldEqual, "Flow=f1")
+			So(e.Message(), ShouldEqual, "Computing 5 things\n  indented")
+			So(e.Fields, ShouldResemble, Fields{{"block", "add Foo"}, {"msg", "shadowed"}})
+			So(time.Since(e.Time), ShouldBeLessThan, time.Minute)
+		})
+	})
+}
@@ -40,11 +40,23 @@ type Logger interface {
 	Info(vals ...interface{})
 	Infof(fmt string, args ...interface{})
 
+	Warn(vals ...interface{})
+	Warnf(fmt string, args ...interface{})
+
 	Error(vals ...interface{})
 	Errorf(fmt string, args ...interface{})
 
 	LogLevel() Level
 	SetLogLevel(Level)
+
+	// Enabled reports whether an entry at the given level would be logged.
+	// Use it to skip work that is only needed for logging.
+	Enabled(level Level) bool
+
+	// With returns a derived Logger that attaches the given alternating keys
+	// and values to every entry it logs, in addition to any fields already
+	// carried by this Logger.
+	With(keyvals ...interface{}) Logger
 }
 
 // System is a single global logger for convenience.  By default, it prints
@@ -55,28 +67,72 @@ var System Logger = NewTextLogger(os.Stderr, binaryName(), TraceLevel)
 func Trace(vals ...interface{})              { System.Trace(vals...) }
 func Debug(vals ...interface{})              { System.Debug(vals...) }
 func Info(vals ...interface{})               { System.Info(vals...) }
+func Warn(vals ...interface{})               { System.Warn(vals...) }
 func Error(vals ...interface{})              { System.Error(vals...) }
 func Tracef(fmt string, args ...interface{}) { System.Tracef(fmt, args...) }
 func Debugf(fmt string, args ...interface{}) { System.Debugf(fmt, args...) }
 func Infof(fmt string, args ...interface{})  { System.Infof(fmt, args...) }
+func Warnf(fmt string, args ...interface{})  { System.Warnf(fmt, args...) }
 func Errorf(fmt string, args ...interface{}) { System.Errorf(fmt, args...) }
+func Enabled(level Level) bool               { return System.Enabled(level) }
 
-// Fatal is a package-level only log function that logs to Error and then exits
-// the process.
+// Fatal is a package-level only log function that logs at FatalLevel and then
+// exits the process.  The Logger interface has no Fatal methods, so only the
+// Loggers of this package can log at FatalLevel; if System is of another type,
+// Fatal logs to its Error method instead.
 func Fatal(vals ...interface{}) {
-	System.Error(vals...)
-	System.Error("Failed at:\n" + stack())
+	st := stack()
+	logFatalf(System, kNO_FORMAT, vals...)
+	logFatalf(System, kNO_FORMAT, "Failed at:\n"+st)
 	os_Exit(-1)
 }
 
-// Fatalf is a package-level only log function that logs to Errorf and then
-// exits the process.
+// Fatalf is a package-level only log function that logs at FatalLevel and then
+// exits the process.  As for Fatal, it logs to Errorf if System is not one of
+// this package's Loggers.
 func Fatalf(fmt string, args ...interface{}) {
-	System.Errorf(fmt, args...)
-	System.Error("Failed at:\n" + stack())
+	st := stack()
+	logFatalf(System, fmt, args...)
+	logFatalf(System, kNO_FORMAT, "Failed at:\n"+st)
 	os_Exit(-1)
 }
 
+// fatalLogger is implemented by the Loggers of this package, which can log at
+// FatalLevel although Logger has no Fatal methods.
+type fatalLogger interface {
+	fatalf(fmt string, params ...interface{})
+}
+
+// logFatalf logs at FatalLevel if l supports it, or else at ErrorLevel.
+func logFatalf(l Logger, fmt string, params ...interface{}) {
+	if f, ok := l.(fatalLogger); ok {
+		f.fatalf(fmt, params...)
+	} else if fmt == kNO_FORMAT {
+		l.Error(params...)
+	} else {
+		l.Errorf(fmt, params...)
+	}
+}
+
+// logAtLevel logs msg to l using the method for the given level.  FatalLevel,
+// which has no Logger method, is logged as an error.
+func logAtLevel(l Logger, level Level, msg string) {
+	switch level {
+	case TraceLevel:
+		l.Trace(msg)
+	case DebugLevel:
+		l.Debug(msg)
+	case InfoLevel:
+		l.Info(msg)
+	case WarnLevel:
+		l.Warn(msg)
+	case FatalLevel:
+		logFatalf(l, kNO_FORMAT, msg)
+	default:
+		l.Error(msg)
+	}
+}
+
 func FatalOnErr(err error) {
 	if err == nil {
 		return
@@ -91,25 +147,47 @@ type Writer interface {
 
 // Entry is a single log entry.
 type Entry struct {
-	Level   Level
-	Time    time.Time
-	File    string // empty string indicates unknown
-	Line    int    // -1 indicates unknown
-	Context string
-	Fmt     string
-	Args    []interface{}
+	Level    Level
+	Time     time.Time
+	File     string // empty string indicates unknown
+	Line     int    // -1 indicates unknown
+	Function string // package-qualified name of the logging function, if known
+	Context  string
+	Fmt      string
+	Args     []interface{}
+	Fields   Fields // structured key/value pairs; may be nil
+}
+
+// Message renders the entry's format string and arguments the same way
+// TextWriter does, without the trailing newline.
+func (e Entry) Message() string {
+	if e.Fmt == kNO_FORMAT {
+		return strings.TrimSuffix(fmt.Sprintln(e.Args...), "\n")
+	}
+	return fmt.Sprintf(e.Fmt, e.Args...)
 }
 
-// Level describes the log level.
+// Level describes the log level.  Levels are ordered from least to most
+// severe, and loggers drop entries below their level.
+//
+// Adding WarnLevel and FatalLevel renumbered ErrorLevel from 4 to 5.  Levels
+// saved or configured as numbers before then must be converted: 4, which used
+// to be ErrorLevel, now means WarnLevel.  Store and configure levels by name,
+// as returned by Name and accepted by ParseLevel, rather than by number.
 type Level int32
 
 const (
 	TraceLevel = Level(1)
 	DebugLevel = Level(2)
 	InfoLevel  = Level(3)
-	ErrorLevel = Level(4)
+	WarnLevel  = Level(4)
+	ErrorLevel = Level(5)
+	FatalLevel = Level(6)
 )
 
+// allLevels lists every valid level, from least to most severe.
+var allLevels = []Level{TraceLevel, DebugLevel, InfoLevel, WarnLevel, ErrorLevel, FatalLevel}
+
 func (l Level) String() string {
 	switch l {
 	case TraceLevel:
@@ -118,12 +196,38 @@ func (l Level) String() string {
 		return "D"
 	case InfoLevel:
 		return "I"
+	case WarnLevel:
+		return "W"
 	case ErrorLevel:
 		return "E"
+	case FatalLevel:
+		return "F"
 	}
 	panic(fmt.Errorf("No such log level: %d", l))
 }
 
+// Name returns the lower-case name of the level, e.g. "info".  It is the
+// long form accepted by ParseLevel.  Unlike String, it does not panic for
+// invalid levels, such as the zero Level, but returns e.g. "Level(0)", so that
+// writers can always render the level of an entry.
+func (l Level) Name() string {
+	switch l {
+	case TraceLevel:
+		return "trace"
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	case FatalLevel:
+		return "fatal"
+	}
+	return fmt.Sprintf("Level(%d)", int32(l))
+}
+
 // ParseLevelOrDie parses the string into a Level.  If the string is invalid, it
 // panics.
 func ParseLevelOrDie(levelstr string) Level {
@@ -144,8 +248,12 @@ func ParseLevel(levelstr string) (Level, error) {
 		return DebugLevel, nil
 	case "i", "info":
 		return InfoLevel, nil
+	case "w", "warn", "warning":
+		return WarnLevel, nil
 	case "e", "error":
 		return ErrorLevel, nil
+	case "f", "fatal":
+		return FatalLevel, nil
 	}
 	return ErrorLevel, fmt.Errorf("Unknown level: %q", levelstr)
 }
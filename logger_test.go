@@ -3,6 +3,7 @@ package logging
 import (
 	"bytes"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -20,7 +21,7 @@ func TestLogFatalAndFatalf(t *testing.T) {
 			Convey("should log the content as an error", func() {
 				So(buf.String(), ShouldContainSubstring, "xyz\n")
 				So(buf.String(), ShouldContainSubstring, "logger_test.go")
-				So(buf.String(), ShouldStartWith, ErrorLevel.String())
+				So(buf.String(), ShouldStartWith, FatalLevel.String())
 			})
 			Convey("should exit the process with a non-zero return code", func() {
 				So(exit_code, ShouldEqual, -1)
@@ -31,7 +32,7 @@ func TestLogFatalAndFatalf(t *testing.T) {
 			Convey("should log the content as an error", func() {
 				So(buf.String(), ShouldContainSubstring, "x:17 s:qrs\n")
 				So(buf.String(), ShouldContainSubstring, "logger_test.go")
-				So(buf.String(), ShouldStartWith, ErrorLevel.String())
+				So(buf.String(), ShouldStartWith, FatalLevel.String())
 			})
 			Convey("should exit the process with a non-zero return code", func() {
 				So(exit_code, ShouldEqual, -1)
@@ -40,6 +41,38 @@ func TestLogFatalAndFatalf(t *testing.T) {
 	})
 }
 
+func TestLogFatalWithoutStdLogger(t *testing.T) {
+	var exit_code int
+	var saved Logger
+	os_Exit = func(code int) { exit_code = code }
+	System, saved = NewMemLogger(), System
+	defer func() { System = saved }()
+	Convey(".Fatal on a System that is not a StdLogger", t, func() {
+		Fatal("xyz")
+		Convey("should log the content at FatalLevel and exit", func() {
+			msgs := System.(*MemLogger).ExtractMsgs()
+			So(msgs[0], ShouldResemble, LogMessage{Loglevel: FatalLevel, Msg: "xyz"})
+			So(exit_code, ShouldEqual, -1)
+		})
+		Convey("should reach FatalLevel through wrapping loggers", func() {
+			mem := NewMemLogger()
+			System = NewSampledLogger(&CancellableLogger{Logger: NewTeeLogger(mem)}, SamplePolicy{Burst: 1, Interval: time.Minute})
+			Fatalf("x:%d", 1)
+			Fatalf("x:%d", 2)
+			msgs := mem.ExtractMsgs()
+			So(msgs, ShouldHaveLength, 4)
+			So(msgs[2], ShouldResemble, LogMessage{Loglevel: FatalLevel, Msg: "x:2"})
+		})
+		Convey("should log to Error on other loggers", func() {
+			var count atomicLogger
+			System = &count
+			Fatal("xyz")
+			So(count, ShouldEqual, 2)
+			So(exit_code, ShouldEqual, -1)
+		})
+	})
+}
+
 func TestLevel(t *testing.T) {
 	Convey("Level", t, func() {
 		Convey("should convert to string", func() {
@@ -47,14 +80,41 @@ func TestLevel(t *testing.T) {
 		})
 		Convey("should be comparable", func() {
 			So(InfoLevel, ShouldBeGreaterThan, DebugLevel)
+			So(WarnLevel, ShouldBeBetween, InfoLevel, ErrorLevel)
+			So(FatalLevel, ShouldBeGreaterThan, ErrorLevel)
+		})
+		Convey("should round-trip through its names", func() {
+			for _, l := range allLevels {
+				So(ParseLevelOrDie(l.String()), ShouldEqual, l)
+				So(ParseLevelOrDie(l.Name()), ShouldEqual, l)
+			}
+		})
+		Convey("should name invalid levels without panicking", func() {
+			So(Level(0).Name(), ShouldEqual, "Level(0)")
+			So(Level(42).Name(), ShouldEqual, "Level(42)")
+			_, err := ParseLevel(Level(0).Name())
+			So(err, ShouldNotBeNil)
 		})
 		Convey("should be created via ParseLeveLOrDie", func() {
 			So(ParseLevelOrDie("InFo"), ShouldEqual, InfoLevel)
 			So(ParseLevelOrDie("t"), ShouldEqual, TraceLevel)
 			So(ParseLevelOrDie("ERROR"), ShouldEqual, ErrorLevel)
 			So(ParseLevelOrDie("debug"), ShouldEqual, DebugLevel)
+			So(ParseLevelOrDie("Warning"), ShouldEqual, WarnLevel)
+			So(ParseLevelOrDie("fatal"), ShouldEqual, FatalLevel)
 			So(func() { ParseLevelOrDie("asdf") }, ShouldPanic)
 			So(func() { ParseLevelOrDie("") }, ShouldPanic)
 		})
 	})
 }
+
+func TestEntryMessage(t *testing.T) {
+	Convey("Entry.Message", t, func() {
+		Convey("should render unformatted args like TextWriter", func() {
+			So(Entry{Fmt: kNO_FORMAT, Args: args("a", 1, "b")}.Message(), ShouldEqual, "a 1 b")
+		})
+		Convey("should apply the format string", func() {
+			So(Entry{Fmt: "%s=%d", Args: args("a", 1)}.Message(), ShouldEqual, "a=1")
+		})
+	})
+}
@@ -1,82 +1,197 @@
 package logging
 
-import "fmt"
-
-// MemLogger stores an array of logging messages, up to a cap.
+import "sync"
 
+// MemLoggerMaxMsgs is the capacity of a MemLogger created by NewMemLogger.
 const MemLoggerMaxMsgs = 128
 
 type LogMessage struct {
 	Loglevel Level
 	Msg      string
+	Fields   Fields
 }
 
+// MemLogger records the most recent log entries in memory, up to a fixed
+// capacity.  Once full, each new entry overwrites the oldest one and is counted
+// as dropped, so a MemLogger can serve as a flight recorder that is dumped to a
+// real Writer after a crash.  MemLogger is safe for concurrent use.  The zero
+// value is ready to use and keeps MemLoggerMaxMsgs entries.
+//
+// Messages are formatted when logged, so that values changed afterwards are
+// recorded as they were; recorded entries hold the message as their only
+// argument.
 type MemLogger struct {
-	msgs []LogMessage
+	once   sync.Once
+	logger *StdLogger
+	// ring is shared by all loggers derived from the same MemLogger.
+	ring *memRing
 }
 
 func NewMemLogger() *MemLogger {
-	return &MemLogger{msgs: make([]LogMessage, 0, MemLoggerMaxMsgs)}
+	return NewMemLoggerWithCapacity(MemLoggerMaxMsgs)
 }
 
-func (l *MemLogger) Trace(vals ...interface{}) {
-	l.appendToLogIfRoom(LogMessage{TraceLevel, fmt.Sprint(vals...)})
-}
-func (l *MemLogger) Debug(vals ...interface{}) {
-	l.appendToLogIfRoom(LogMessage{DebugLevel, fmt.Sprint(vals...)})
+// std returns the logger that records into the ring, creating both for the
+// zero value.
+func (l *MemLogger) std() *StdLogger {
+	l.once.Do(func() {
+		if l.logger == nil {
+			l.ring = &memRing{entries: make([]Entry, MemLoggerMaxMsgs)}
+			l.logger = &StdLogger{Writer: l.ring, MinLevel: TraceLevel}
+		}
+	})
+	return l.logger
 }
-func (l *MemLogger) Info(vals ...interface{}) {
-	l.appendToLogIfRoom(LogMessage{InfoLevel, fmt.Sprint(vals...)})
+
+// memRing returns the ring that l records into.
+func (l *MemLogger) memRing() *memRing {
+	l.std()
+	return l.ring
 }
-func (l *MemLogger) Error(vals ...interface{}) {
-	l.appendToLogIfRoom(LogMessage{ErrorLevel, fmt.Sprint(vals...)})
+
+// NewMemLoggerWithCapacity returns a MemLogger that keeps the last capacity
+// entries.  It logs all levels until changed with SetLogLevel.  A negative
+// capacity is treated as 0, which keeps no entries and only counts them.
+func NewMemLoggerWithCapacity(capacity int) *MemLogger {
+	if capacity < 0 {
+		capacity = 0
+	}
+	ring := &memRing{entries: make([]Entry, capacity)}
+	return &MemLogger{logger: &StdLogger{Writer: ring, MinLevel: TraceLevel}, ring: ring}
 }
 
+func (l *MemLogger) Trace(vals ...interface{}) { l.std().stdlogf(TraceLevel, kNO_FORMAT, vals...) }
+func (l *MemLogger) Debug(vals ...interface{}) { l.std().stdlogf(DebugLevel, kNO_FORMAT, vals...) }
+func (l *MemLogger) Info(vals ...interface{})  { l.std().stdlogf(InfoLevel, kNO_FORMAT, vals...) }
+func (l *MemLogger) Warn(vals ...interface{})  { l.std().stdlogf(WarnLevel, kNO_FORMAT, vals...) }
+func (l *MemLogger) Error(vals ...interface{}) { l.std().stdlogf(ErrorLevel, kNO_FORMAT, vals...) }
+
 func (l *MemLogger) Tracef(format string, params ...interface{}) {
-	l.appendToLogIfRoom(LogMessage{TraceLevel, fmt.Sprintf(format, params...)})
+	l.std().stdlogf(TraceLevel, format, params...)
 }
 func (l *MemLogger) Debugf(format string, params ...interface{}) {
-	l.appendToLogIfRoom(LogMessage{DebugLevel, fmt.Sprintf(format, params...)})
+	l.std().stdlogf(DebugLevel, format, params...)
 }
 func (l *MemLogger) Infof(format string, params ...interface{}) {
-	l.appendToLogIfRoom(LogMessage{InfoLevel, fmt.Sprintf(format, params...)})
+	l.std().stdlogf(InfoLevel, format, params...)
+}
+func (l *MemLogger) Warnf(format string, params ...interface{}) {
+	l.std().stdlogf(WarnLevel, format, params...)
 }
 func (l *MemLogger) Errorf(format string, params ...interface{}) {
-	l.appendToLogIfRoom(LogMessage{ErrorLevel, fmt.Sprintf(format, params...)})
+	l.std().stdlogf(ErrorLevel, format, params...)
 }
 
-func (l *MemLogger) appendToLogIfRoom(msg LogMessage) {
-	if len(l.msgs) < MemLoggerMaxMsgs {
-		l.msgs = append(l.msgs, msg)
-	}
+func (l *MemLogger) fatalf(format string, params ...interface{}) {
+	l.std().stdlogf(FatalLevel, format, params...)
 }
 
-func (l *MemLogger) SetLogLevel(newLevel Level) {
-	// Store them all.
+func (l *MemLogger) SetLogLevel(newLevel Level) { l.std().SetLogLevel(newLevel) }
+func (l *MemLogger) LogLevel() Level            { return l.std().LogLevel() }
+func (l *MemLogger) Enabled(level Level) bool   { return level >= l.std().LogLevel() }
+
+// With returns a MemLogger that records into the same buffer as l, with the
+// given key/value pairs attached to each entry.
+func (l *MemLogger) With(keyvals ...interface{}) Logger {
+	logger := l.std().with(keyvals...)
+	return &MemLogger{logger: logger, ring: l.ring}
 }
-func (l *MemLogger) LogLevel() Level {
-	return TraceLevel
+
+func (l *MemLogger) WithCallerSkip(n int) Logger {
+	logger := l.std().with()
+	logger.CallerSkip += n
+	return &MemLogger{logger: logger, ring: l.ring}
 }
 
+// ExtractEntries returns the recorded entries, oldest first, and clears the
+// buffer.
+func (l *MemLogger) ExtractEntries() []Entry { return l.memRing().extract(true) }
+
+// Entries returns the recorded entries, oldest first, leaving them in place.
+func (l *MemLogger) Entries() []Entry { return l.memRing().extract(false) }
+
+// ExtractMsgs returns the recorded messages, oldest first, and clears the
+// buffer.
 func (l *MemLogger) ExtractMsgs() []LogMessage {
-	retVal := l.msgs
-	l.msgs = make([]LogMessage, 0, MemLoggerMaxMsgs)
-	return retVal
+	entries := l.ExtractEntries()
+	msgs := make([]LogMessage, len(entries))
+	for i, e := range entries {
+		msgs[i] = LogMessage{Loglevel: e.Level, Msg: e.Message(), Fields: e.Fields}
+	}
+	return msgs
+}
+
+// Dropped returns the number of entries that were overwritten before being
+// extracted.
+func (l *MemLogger) Dropped() uint64 {
+	r := l.memRing()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.dropped
+}
+
+// Dump writes the recorded entries, oldest first, to w without clearing them.
+func (l *MemLogger) Dump(w Writer) error {
+	for _, e := range l.Entries() {
+		if err := w.Write(e); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 var _ Logger = &MemLogger{}
 
+// memRing is a fixed-size ring buffer of entries.
+type memRing struct {
+	mutex   sync.Mutex
+	entries []Entry
+	start   int // index of the oldest entry
+	n       int // number of valid entries
+	dropped uint64
+}
+
+func (r *memRing) Write(e Entry) error {
+	e.Fmt, e.Args = kNO_FORMAT, []interface{}{e.Message()}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.entries) == 0 {
+		r.dropped++
+		return nil
+	}
+	if r.n == len(r.entries) {
+		r.entries[r.start] = e
+		r.start = (r.start + 1) % len(r.entries)
+		r.dropped++
+		return nil
+	}
+	r.entries[(r.start+r.n)%len(r.entries)] = e
+	r.n++
+	return nil
+}
+
+func (r *memRing) extract(reset bool) []Entry {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	out := make([]Entry, r.n)
+	for i := range out {
+		out[i] = r.entries[(r.start+i)%len(r.entries)]
+	}
+	if reset {
+		for i := range r.entries {
+			r.entries[i] = Entry{} // release references to args
+		}
+		r.start, r.n = 0, 0
+	}
+	return out
+}
+
 func WriteLogMessageArray(logger Logger, msgs []LogMessage) {
 	for _, msg := range msgs {
-		switch msg.Loglevel {
-		case TraceLevel:
-			logger.Trace(msg.Msg)
-		case DebugLevel:
-			logger.Debug(msg.Msg)
-		case InfoLevel:
-			logger.Info(msg.Msg)
-		case ErrorLevel:
-			logger.Error(msg.Msg)
+		l := logger
+		if len(msg.Fields) > 0 {
+			l = logger.With(msg.Fields.keyvals()...)
 		}
+		logAtLevel(l, msg.Loglevel, msg.Msg)
 	}
 }
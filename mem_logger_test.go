@@ -1,7 +1,9 @@
 package logging
 
 import (
+	"bytes"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -13,43 +15,139 @@ func TestMemLogger(t *testing.T) {
 		Convey("Records trace message and level", func() {
 			memlogger.Trace("Bad News Bears")
 			msgs := memlogger.ExtractMsgs()
-			So(msgs[0], ShouldResemble, LogMessage{TraceLevel, "Bad News Bears"})
+			So(msgs[0], ShouldResemble, LogMessage{Loglevel: TraceLevel, Msg: "Bad News Bears"})
 		})
 		Convey("Records debug message and level", func() {
 			memlogger.Debug("Bad News Bears")
 			msgs := memlogger.ExtractMsgs()
-			So(msgs[0], ShouldResemble, LogMessage{DebugLevel, "Bad News Bears"})
+			So(msgs[0], ShouldResemble, LogMessage{Loglevel: DebugLevel, Msg: "Bad News Bears"})
 		})
 		Convey("Records info message and level", func() {
 			memlogger.Info("Bad News Bears")
 			msgs := memlogger.ExtractMsgs()
-			So(msgs[0], ShouldResemble, LogMessage{InfoLevel, "Bad News Bears"})
+			So(msgs[0], ShouldResemble, LogMessage{Loglevel: InfoLevel, Msg: "Bad News Bears"})
+		})
+		Convey("Records warn message and level", func() {
+			memlogger.Warn("Bad News Bears")
+			msgs := memlogger.ExtractMsgs()
+			So(msgs[0], ShouldResemble, LogMessage{Loglevel: WarnLevel, Msg: "Bad News Bears"})
 		})
 		Convey("Records error message and level", func() {
 			memlogger.Error("Bad News Bears")
 			msgs := memlogger.ExtractMsgs()
-			So(msgs[0], ShouldResemble, LogMessage{ErrorLevel, "Bad News Bears"})
+			So(msgs[0], ShouldResemble, LogMessage{Loglevel: ErrorLevel, Msg: "Bad News Bears"})
 		})
 
 		Convey("Records formatted trace message and level", func() {
 			memlogger.Tracef("Bad News Bears: %s", "Ruxbin")
 			msgs := memlogger.ExtractMsgs()
-			So(msgs[0], ShouldResemble, LogMessage{TraceLevel, "Bad News Bears: Ruxbin"})
+			So(msgs[0], ShouldResemble, LogMessage{Loglevel: TraceLevel, Msg: "Bad News Bears: Ruxbin"})
 		})
 		Convey("Records formatted debug message and level", func() {
 			memlogger.Debugf("Bad News Bears: %s", "Ruxbin")
 			msgs := memlogger.ExtractMsgs()
-			So(msgs[0], ShouldResemble, LogMessage{DebugLevel, "Bad News Bears: Ruxbin"})
+			So(msgs[0], ShouldResemble, LogMessage{Loglevel: DebugLevel, Msg: "Bad News Bears: Ruxbin"})
 		})
 		Convey("Records formatted info message and level", func() {
 			memlogger.Infof("Bad News Bears: %s", "Ruxbin")
 			msgs := memlogger.ExtractMsgs()
-			So(msgs[0], ShouldResemble, LogMessage{InfoLevel, "Bad News Bears: Ruxbin"})
+			So(msgs[0], ShouldResemble, LogMessage{Loglevel: InfoLevel, Msg: "Bad News Bears: Ruxbin"})
+		})
+		Convey("Records formatted warn message and level", func() {
+			memlogger.Warnf("Bad News Bears: %s", "Ruxbin")
+			msgs := memlogger.ExtractMsgs()
+			So(msgs[0], ShouldResemble, LogMessage{Loglevel: WarnLevel, Msg: "Bad News Bears: Ruxbin"})
 		})
 		Convey("Records formatted error message and level", func() {
 			memlogger.Errorf("Bad News Bears: %s", "Ruxbin")
 			msgs := memlogger.ExtractMsgs()
-			So(msgs[0], ShouldResemble, LogMessage{ErrorLevel, "Bad News Bears: Ruxbin"})
+			So(msgs[0], ShouldResemble, LogMessage{Loglevel: ErrorLevel, Msg: "Bad News Bears: Ruxbin"})
+		})
+
+		Convey("Records fields of derived loggers in the shared store", func() {
+			memlogger.With("block", "addFoo").Info("Bad News Bears")
+			memlogger.Info("Ruxbin")
+			msgs := memlogger.ExtractMsgs()
+			So(msgs, ShouldResemble, []LogMessage{
+				{Loglevel: InfoLevel, Msg: "Bad News Bears", Fields: Fields{{"block", "addFoo"}}},
+				{Loglevel: InfoLevel, Msg: "Ruxbin"},
+			})
+		})
+		Convey("Records the origin and time of each entry", func() {
+			t0 := time.Now()
+			memlogger.Info("Bad News Bears")
+			entries := memlogger.ExtractEntries()
+			So(entries[0].File, ShouldContainSubstring, "mem_logger_test.go")
+			So(entries[0].Line, ShouldBeGreaterThan, 0)
+			So(entries[0].Time, ShouldHappenOnOrAfter, t0)
+		})
+		Convey("Honours the log level", func() {
+			memlogger.SetLogLevel(InfoLevel)
+			memlogger.Debug("dropped")
+			memlogger.With("k", "v").Debug("dropped")
+			memlogger.Info("kept")
+			So(memlogger.LogLevel(), ShouldEqual, InfoLevel)
+			So(memlogger.ExtractMsgs(), ShouldResemble, []LogMessage{{Loglevel: InfoLevel, Msg: "kept"}})
+		})
+		Convey("Keeps the newest entries and counts the dropped ones", func() {
+			small := NewMemLoggerWithCapacity(3)
+			for i := 0; i < 5; i++ {
+				small.Infof("%d", i)
+			}
+			So(small.Dropped(), ShouldEqual, 2)
+			So(small.ExtractMsgs(), ShouldResemble, []LogMessage{
+				{Loglevel: InfoLevel, Msg: "2"},
+				{Loglevel: InfoLevel, Msg: "3"},
+				{Loglevel: InfoLevel, Msg: "4"},
+			})
+			So(small.ExtractMsgs(), ShouldBeEmpty)
+			small.Info("5")
+			So(small.ExtractMsgs(), ShouldResemble, []LogMessage{{Loglevel: InfoLevel, Msg: "5"}})
+		})
+		Convey("Dumps entries to a Writer without clearing them", func() {
+			var buf bytes.Buffer
+			memlogger.Error("Bad News Bears")
+			So(memlogger.Dump(&TextWriter{Writer: &buf}), ShouldBeNil)
+			So(buf.String(), ShouldStartWith, "E")
+			So(buf.String(), ShouldContainSubstring, "mem_logger_test.go")
+			So(buf.String(), ShouldEndWith, "Bad News Bears\n")
+			So(memlogger.Entries(), ShouldHaveLength, 1)
+		})
+		Convey("Records arguments as they were when logged", func() {
+			vals := []int{1, 2}
+			memlogger.Infof("vals=%v", vals)
+			vals[0] = 42
+			So(memlogger.ExtractMsgs()[0].Msg, ShouldEqual, "vals=[1 2]")
+		})
+		Convey("Works as a zero value", func() {
+			var zero MemLogger
+			zero.With("k", "v").Info("a")
+			zero.Warn("b")
+			So(zero.ExtractMsgs(), ShouldResemble, []LogMessage{
+				{Loglevel: InfoLevel, Msg: "a", Fields: Fields{{"k", "v"}}},
+				{Loglevel: WarnLevel, Msg: "b"},
+			})
+			So(zero.Dropped(), ShouldEqual, 0)
+		})
+		Convey("Treats a negative capacity as zero", func() {
+			none := NewMemLoggerWithCapacity(-1)
+			none.Info("a")
+			So(none.Entries(), ShouldBeEmpty)
+			So(none.Dropped(), ShouldEqual, 1)
+		})
+		Convey("Is safe for concurrent use", func() {
+			small := NewMemLoggerWithCapacity(10)
+			executeWithMaximumContention(20, func(i int) {
+				small.With("i", i).Infof("%d", i)
+			})
+			So(small.Entries(), ShouldHaveLength, 10)
+			So(small.Dropped(), ShouldEqual, 10)
+		})
+		Convey("Replays fields with WriteLogMessageArray", func() {
+			var c captureWriter
+			memlogger.With("block", "addFoo").Error("Bad News Bears")
+			WriteLogMessageArray(&StdLogger{Writer: &c, MinLevel: TraceLevel}, memlogger.ExtractMsgs())
+			So(c.Fields, ShouldResemble, Fields{{"block", "addFoo"}})
 		})
 	})
 }
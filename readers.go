@@ -2,13 +2,19 @@ package logging
 
 import (
 	"bufio"
-	"bytes"
 	"fmt"
 	"io"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// FilteringLogReader is an io.Reader over text log output (as produced by
+// TextWriter) that passes through only the entries whose context matches a
+// regular expression.  Entries are kept or dropped as a whole: the header line
+// together with all of its continuation lines.  Any lines before the first
+// entry header are dropped.
 type FilteringLogReader struct {
 	reader        *bufio.Reader
 	contextRegexp *regexp.Regexp
@@ -16,46 +22,70 @@ type FilteringLogReader struct {
 	skipEntry     bool
 }
 
-func (f *FilteringLogReader) Read(buf []byte) (n int, err error) {
-	if len(f.buf) > 0 {
-		n = copy(buf, f.buf)
-		f.buf = f.buf[n:]
-		return n, nil
+// NewFilteringLogReader returns a reader that yields the entries of r whose
+// context matches contextRegexp.
+func NewFilteringLogReader(r io.Reader, contextRegexp *regexp.Regexp) *FilteringLogReader {
+	return &FilteringLogReader{
+		reader:        bufio.NewReader(r),
+		contextRegexp: contextRegexp,
+		skipEntry:     true,
 	}
+}
 
-	var prefix bool
-	var haveNewLine bool
-	for f.skipEntry {
-		f.buf, prefix, err = f.reader.ReadLine()
+func (f *FilteringLogReader) Read(buf []byte) (n int, err error) {
+	for len(f.buf) == 0 {
 		if err != nil {
 			return 0, err
 		}
 
-		if haveNewLine {
-			// When we've read a new line, check to see if it's a continuation.
-			// If so, keep skipping it.
-			f.skipEntry = bytes.HasPrefix(f.buf, []byte(continuation))
+		var line []byte
+		line, err = f.reader.ReadBytes('\n')
+		if len(line) == 0 {
+			continue
 		}
 
-		haveNewLine = !prefix
-	}
-	// If we haven't read anything in yet, read in some data.
-	if !haveNewLine {
-		f.buf, prefix, err = f.reader.ReadLine()
+		// Only a new header changes whether we're keeping the current entry;
+		// continuation lines (and anything unparseable) follow their header.
+		if lineType, e := determineLineType(string(line)); lineType == entryStart {
+			f.skipEntry = !f.contextRegexp.MatchString(e.context)
+		}
+		if !f.skipEntry {
+			f.buf = line
+		}
 	}
 
-	return 0, io.EOF
+	n = copy(buf, f.buf)
+	f.buf = f.buf[n:]
+	return n, nil
 }
 
+// LogReader parses text log output (as produced by TextWriter) back into
+// entries, so that logs can be filtered or re-written with any Writer.
+//
+// Text timestamps do not record a year.  Each timestamp is assigned the most
+// recent year that does not place it more than a day after Reference, or after
+// the current time if Reference is zero.  Logs read within a year of being
+// written therefore resolve correctly, including logs that span New Year's
+// Day.  February 29th resolves to the most recent leap year.
+//
+// By default, a line that cannot be parsed aborts the read with an error.  In
+// lenient mode, such lines are instead returned as raw entries: InfoLevel, a
+// zero Time, an unknown origin (File "" and Line -1), an empty context and the
+// unparsed line as the message.
 type LogReader struct {
-	reader     *bufio.Reader
-	linebuffer bytes.Buffer
+	// Reference is the time used to resolve the year of timestamps.
+	Reference time.Time
+	// Lenient makes Next return unparseable lines as raw entries.
+	Lenient bool
+
+	reader *bufio.Reader
 }
 
 func NewLogReader(r io.Reader) *LogReader {
 	return &LogReader{reader: bufio.NewReader(r)}
 }
 
+// logEntry is a single parsed entry, with all fields as they appear in text.
 type logEntry struct {
 	full string
 
@@ -65,11 +95,27 @@ type logEntry struct {
 	file    string
 	line    string
 	context string
+	fields  string
 
 	msg string
+
+	// unparsed is set for lines that were not recognized in lenient mode.
+	unparsed bool
 }
 
-func (r *LogReader) Next() (entry logEntry, err error) {
+// Next returns the next entry in the log.  The message is returned as the sole
+// argument of an unformatted entry, with the continuation prefixes removed.
+// Field values are returned as strings.  At the end of the log, Next returns
+// io.EOF.
+func (r *LogReader) Next() (Entry, error) {
+	raw, err := r.next()
+	if err != nil {
+		return Entry{}, err
+	}
+	return r.parse(raw)
+}
+
+func (r *LogReader) next() (entry logEntry, err error) {
 	for err == nil {
 		var line string
 		line, err = r.reader.ReadString('\n')
@@ -83,18 +129,21 @@ func (r *LogReader) Next() (entry logEntry, err error) {
 			break
 		}
 
+		inEntry := len(entry.header) > 0 || entry.unparsed
 		lineType, lineEntry := determineLineType(line)
-		if lineType == unknown {
-			return entry, fmt.Errorf("Malformatted log file.  Cannot parse line: %q", line)
-		}
-		if lineType == entryCont && len(lineEntry.header) == 0 {
-			return entry, fmt.Errorf("Starting in the middle of a log file: %q", line)
-		}
-
-		if lineType == entryStart {
+		switch {
+		case lineType == entryStart:
 			entry = lineEntry
-		} else {
+		case lineType == entryCont && inEntry:
 			entry.msg += lineEntry.msg
+		case !r.Lenient && lineType == unknown:
+			return entry, fmt.Errorf("Malformatted log file.  Cannot parse line: %q", line)
+		case !r.Lenient:
+			return entry, fmt.Errorf("Starting in the middle of a log file: %q", line)
+		case inEntry:
+			entry.msg += line
+		default:
+			entry = logEntry{full: line, msg: line, unparsed: true}
 		}
 
 		next, _ := r.reader.Peek(1)
@@ -106,13 +155,100 @@ func (r *LogReader) Next() (entry logEntry, err error) {
 	return entry, err
 }
 
+// parse converts the textual fields of raw into an Entry.  In lenient mode,
+// unparseable entries are returned as raw entries rather than errors.
+func (r *LogReader) parse(raw logEntry) (Entry, error) {
+	if raw.unparsed {
+		return rawEntry(raw.msg), nil
+	}
+	e, err := r.parseEntry(raw)
+	if err != nil && r.Lenient {
+		return rawEntry(raw.header + raw.msg), nil
+	}
+	return e, err
+}
+
+func rawEntry(msg string) Entry {
+	return Entry{Level: InfoLevel, Line: -1, Fmt: kNO_FORMAT, Args: []interface{}{msg}}
+}
+
+func (r *LogReader) parseEntry(raw logEntry) (Entry, error) {
+	level, err := ParseLevel(string(raw.typ))
+	if err != nil {
+		return Entry{}, err
+	}
+	ts, err := r.parseTime(raw.ts)
+	if err != nil {
+		return Entry{}, err
+	}
+	file, line := raw.file, -1
+	if file == "???" {
+		file = ""
+	}
+	if raw.line != "" {
+		if line, err = strconv.Atoi(raw.line); err != nil {
+			return Entry{}, fmt.Errorf("Bad line number in log entry %q: %v", raw.header, err)
+		}
+	}
+	fields, err := parseFields(raw.fields)
+	if err != nil {
+		return Entry{}, fmt.Errorf("Bad fields in log entry %q: %v", raw.header, err)
+	}
+	return Entry{
+		Level:   level,
+		Time:    ts,
+		File:    file,
+		Line:    line,
+		Context: raw.context,
+		Fmt:     kNO_FORMAT,
+		Args:    []interface{}{raw.msg},
+		Fields:  fields,
+	}, nil
+}
+
+// parseTime parses a TextWriter timestamp, resolving the year as described on
+// LogReader.
+func (r *LogReader) parseTime(ts string) (t time.Time, err error) {
+	ref := r.Reference
+	if ref.IsZero() {
+		ref = time.Now()
+	}
+	latest := ref.Add(24 * time.Hour)
+	// Eight years back is enough to find a leap year for February 29th.
+	for year := ref.Year(); year > ref.Year()-8; year-- {
+		t, err = time.Parse("2006 "+textTimestampFormat, fmt.Sprintf("%04d %s", year, ts))
+		if err == nil && !t.After(latest) {
+			return t, nil
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("Cannot resolve year of timestamp %q", ts)
+	}
+	return t, err
+}
+
 const (
 	unknown = iota
 	entryStart
 	entryCont
 )
 
-var entryStartRegexp = regexp.MustCompile(`^(I|D|E)(\d{4} [\d:\.]{12}[-+\dZ]+) ([\w.]+):(\d+) \((.*)\): `)
+// entryStartRegexp matches the header of a TextWriter entry: the level,
+// timestamp, origin (with or without a line number), context and fields.  The
+// context is either quoted, as by appendTextContext, or runs to the first ')'
+// that is followed by the fields or the message.  The fields run to the first
+// "]: " outside of quoted values.
+var entryStartRegexp = regexp.MustCompile(`^(` + levelLetters() + `)(\d{4} [\d:\.]{12}[-+\dZ]+) ([^\s:]+)(?::(\d+))? \(("(?:[^"\\]|\\.)*"|.*?)\)(?: \[((?:"(?:[^"\\]|\\.)*"|[^"])*?)\])?: `)
+
+// levelLetters returns a regexp alternation of the single-letter names of all
+// levels.
+func levelLetters() string {
+	letters := make([]string, len(allLevels))
+	for i, l := range allLevels {
+		letters[i] = l.String()
+	}
+	return strings.Join(letters, "|")
+}
 
 func determineLineType(line string) (lineType int, e logEntry) {
 	if len(line) < len(continuation) {
@@ -127,7 +263,19 @@ func determineLineType(line string) (lineType int, e logEntry) {
 		return unknown, e
 	}
 
-	part := func(n int) string { return line[headerPos[n*2]:headerPos[n*2+1]] }
+	part := func(n int) string {
+		if headerPos[n*2] < 0 {
+			return ""
+		}
+		return line[headerPos[n*2]:headerPos[n*2+1]]
+	}
+
+	context := part(5)
+	if strings.HasPrefix(context, `"`) {
+		if unquoted, err := strconv.Unquote(context); err == nil {
+			context = unquoted
+		}
+	}
 
 	e = logEntry{
 		full:    line,
@@ -136,9 +284,107 @@ func determineLineType(line string) (lineType int, e logEntry) {
 		ts:      part(2),
 		file:    part(3),
 		line:    part(4),
-		context: part(5),
+		context: context,
+		fields:  part(6),
 		msg:     line[headerPos[1]:],
 	}
 
 	return entryStart, e
 }
+
+// LogfmtReader parses logfmt output, as produced by LogfmtWriter, back into
+// entries.  The keys level, ts, caller, context and msg fill in the entry and
+// all other keys become its fields, with string values.  Only the first
+// occurrence of each of those keys is taken for the entry, so fields that share
+// their names survive a round trip.  A line without level is read at
+// InfoLevel, and one without caller has an unknown origin.
+//
+// By default, a line that cannot be parsed aborts the read with an error.  In
+// lenient mode, such lines are instead returned as raw entries, as described on
+// LogReader.
+type LogfmtReader struct {
+	// Lenient makes Next return unparseable lines as raw entries.
+	Lenient bool
+
+	reader *bufio.Reader
+}
+
+func NewLogfmtReader(r io.Reader) *LogfmtReader {
+	return &LogfmtReader{reader: bufio.NewReader(r)}
+}
+
+// Next returns the next entry in the log.  The message is returned as the sole
+// argument of an unformatted entry.  Blank lines are skipped.  At the end of the
+// log, Next returns io.EOF.
+func (r *LogfmtReader) Next() (Entry, error) {
+	for {
+		line, err := r.reader.ReadString('\n')
+		// As in LogReader.next, a last line without a newline is still a line.
+		if len(line) > 0 && err == io.EOF {
+			err = nil
+		} else if err != nil {
+			return Entry{}, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		e, err := parseLogfmtEntry(line)
+		if err != nil && r.Lenient {
+			return rawEntry(line), nil
+		}
+		return e, err
+	}
+}
+
+func parseLogfmtEntry(line string) (Entry, error) {
+	fields, err := parseFields(line)
+	if err != nil {
+		return Entry{}, fmt.Errorf("Bad logfmt line %q: %v", line, err)
+	}
+	e := Entry{Level: InfoLevel, Line: -1, Fmt: kNO_FORMAT, Args: []interface{}{""}}
+	seen := map[string]bool{}
+	for _, f := range fields {
+		val := f.Value.(string)
+		if seen[f.Key] {
+			e.Fields = append(e.Fields, f)
+			continue
+		}
+		switch f.Key {
+		case "level":
+			e.Level, err = ParseLevel(val)
+		case "ts":
+			e.Time, err = time.Parse(time.RFC3339Nano, val)
+		case "caller":
+			e.File, e.Line, err = parseCaller(val)
+		case "context":
+			e.Context = val
+		case "msg":
+			e.Args = []interface{}{val}
+		default:
+			e.Fields = append(e.Fields, f)
+			continue
+		}
+		if err != nil {
+			return Entry{}, fmt.Errorf("Bad %s in logfmt line %q: %v", f.Key, line, err)
+		}
+		seen[f.Key] = true
+	}
+	return e, nil
+}
+
+// parseCaller parses an origin as written by appendOrigin: a file name,
+// optionally followed by a colon and a line number.
+func parseCaller(s string) (file string, line int, err error) {
+	file, line = s, -1
+	if i := strings.LastIndexByte(s, ':'); i >= 0 {
+		if line, err = strconv.Atoi(s[i+1:]); err != nil {
+			return "", -1, err
+		}
+		file = s[:i]
+	}
+	if file == "???" {
+		file = ""
+	}
+	return file, line, nil
+}
@@ -1,9 +1,14 @@
 package logging
 
 import (
+	"bytes"
 	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -20,31 +25,293 @@ I0101 00:00:00.000Z std_logger_test.go:00 (Flow=f1): Here's some JSON:
 I0101 00:00:00.000Z std_logger_test.go:00 (Block=addFoo): Computing 5 things
 `[1:]
 
+	ref := time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC)
+	entryAt := func(ctx string, msg string) Entry {
+		return Entry{
+			Level:   InfoLevel,
+			Time:    time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC),
+			File:    "std_logger_test.go",
+			Line:    0,
+			Context: ctx,
+			Fmt:     kNO_FORMAT,
+			Args:    args(msg),
+		}
+	}
+
 	Convey("LogReader should read each log message.", t, func() {
 		r := NewLogReader(strings.NewReader(logContent))
+		r.Reference = ref
 		entry, err := r.Next()
 		So(err, ShouldBeNil)
-		So(entry.header, ShouldEqual, "I0101 00:00:00.000Z std_logger_test.go:00 (Flow=f1): ")
-		So(entry.msg, ShouldEqual, "Creating 20 blocks")
+		So(entry, ShouldResemble, entryAt("Flow=f1", "Creating 20 blocks"))
 
 		entry, err = r.Next()
 		So(err, ShouldBeNil)
-		So(entry.header, ShouldEqual, "I0101 00:00:00.000Z std_logger_test.go:00 (Flow=f1): ")
 		// Note that the continuation prefix is removed.
-		So(entry.msg, ShouldEqual, `Here's some JSON:
+		So(entry, ShouldResemble, entryAt("Flow=f1", `Here's some JSON:
 {
   "A": "some val",
   "B": "other val"
 }
-Err:<nil>`)
+Err:<nil>`))
 
 		entry, err = r.Next()
 		So(err, ShouldBeNil)
-		So(entry.header, ShouldEqual, "I0101 00:00:00.000Z std_logger_test.go:00 (Block=addFoo): ")
-		So(entry.msg, ShouldEqual, "Computing 5 things")
+		So(entry, ShouldResemble, entryAt("Block=addFoo", "Computing 5 things"))
 
 		entry, err = r.Next()
 		So(err, ShouldEqual, io.EOF)
 	})
 
+	Convey("LogReader should round-trip TextWriter output", t, func() {
+		var buf bytes.Buffer
+		w := &TextWriter{Writer: &buf}
+		in := []Entry{
+			{Level: ErrorLevel, Time: time.Date(2016, 2, 28, 13, 14, 15, 16000000, time.UTC), File: "a.go", Line: 7,
+				Context: "Flow=f1", Fmt: "%s\n%d", Args: args("multi", 2)},
+			{Level: DebugLevel, Time: time.Date(2016, 2, 29, 1, 2, 3, 0, time.FixedZone("", -7*3600)), File: "b.go", Line: 12,
+				Context: "Block=addFoo", Fmt: "x", Fields: Fields{{"flow", "f1"}, {"note", "two words"}}},
+		}
+		for _, e := range in {
+			So(w.Write(e), ShouldBeNil)
+		}
+		text := buf.String()
+		buf.Reset()
+
+		r := NewLogReader(strings.NewReader(text))
+		r.Reference = ref
+		for _, e := range in {
+			out, err := r.Next()
+			So(err, ShouldBeNil)
+			So(out.Level, ShouldEqual, e.Level)
+			So(out.Time.Equal(e.Time), ShouldBeTrue)
+			So(out.File, ShouldEqual, e.File)
+			So(out.Line, ShouldEqual, e.Line)
+			So(out.Context, ShouldEqual, e.Context)
+			So(out.Message(), ShouldEqual, e.Message())
+			So(out.Fields.String(), ShouldEqual, e.Fields.String())
+			So(w.Write(out), ShouldBeNil)
+		}
+		So(buf.String(), ShouldEqual, text)
+	})
+
+	Convey("LogReader should resolve the year of timestamps", t, func() {
+		year := func(ref time.Time, ts string) int {
+			r := NewLogReader(strings.NewReader("I" + ts + " a.go:1 (): x\n"))
+			r.Reference = ref
+			e, err := r.Next()
+			So(err, ShouldBeNil)
+			return e.Time.Year()
+		}
+		newYear := time.Date(2017, 1, 1, 0, 10, 0, 0, time.UTC)
+		So(year(newYear, "1231 23:59:59.000Z"), ShouldEqual, 2016)
+		So(year(newYear, "0101 00:05:00.000Z"), ShouldEqual, 2017)
+		// Allow a little clock skew into the future.
+		So(year(newYear, "0101 05:00:00.000Z"), ShouldEqual, 2017)
+		So(year(newYear, "0229 00:00:00.000Z"), ShouldEqual, 2016)
+	})
+}
+
+func TestLogReaderFormats(t *testing.T) {
+	Convey("LogReader should parse every origin and level TextWriter emits", t, func() {
+		var buf bytes.Buffer
+		w := &TextWriter{Writer: &buf}
+		ts := time.Date(2016, 2, 28, 13, 14, 15, 0, time.UTC)
+		in := []Entry{
+			{Level: TraceLevel, Time: ts, File: "/src/my-block_v2.go", Line: 3, Fmt: "dashes"},
+			{Level: DebugLevel, Time: ts, File: "file.go", Line: -1, Fmt: "no line"},
+			{Level: InfoLevel, Time: ts, File: "", Line: -1, Fmt: "unknown origin"},
+		}
+		for _, l := range allLevels {
+			in = append(in, Entry{Level: l, Time: ts, File: "a.go", Line: 1, Fmt: "x"})
+		}
+		for _, e := range in {
+			So(w.Write(e), ShouldBeNil)
+		}
+
+		r := NewLogReader(&buf)
+		r.Reference = ts
+		for _, e := range in {
+			out, err := r.Next()
+			So(err, ShouldBeNil)
+			So(out.Level, ShouldEqual, e.Level)
+			if e.File == "" {
+				So(out.File, ShouldEqual, "")
+			} else {
+				So(out.File, ShouldEqual, filepath.Base(e.File))
+			}
+			So(out.Line, ShouldEqual, e.Line)
+			So(out.Message(), ShouldEqual, e.Fmt)
+		}
+		_, err := r.Next()
+		So(err, ShouldEqual, io.EOF)
+	})
+
+	Convey("LogReader should read back any context", t, func() {
+		var buf bytes.Buffer
+		w := &TextWriter{Writer: &buf}
+		contexts := []string{"", "Flow=f1", "x): y", "f(a) [b]", `"quoted"`, `back\slash)`, "two\nlines"}
+		fields := Fields{{"k", "v"}, {"q", "a]: b"}}
+		for _, c := range contexts {
+			So(w.Write(Entry{Level: InfoLevel, Line: -1, Context: c, Fields: fields, Fmt: "msg): z"}), ShouldBeNil)
+		}
+		r := NewLogReader(&buf)
+		for _, c := range contexts {
+			e, err := r.Next()
+			So(err, ShouldBeNil)
+			So(e.Context, ShouldEqual, c)
+			So(e.Fields, ShouldResemble, fields)
+			So(e.Message(), ShouldEqual, "msg): z")
+		}
+	})
+
+	Convey("LogReader", t, func() {
+		logContent := `
+    orphaned continuation
+I0101 00:00:00.000Z a.go:1 (): first
+garbage line
+I0101 00:00:00.000Z a.go:1 (): second
+    continued
+`[1:]
+		r := NewLogReader(strings.NewReader(logContent))
+
+		Convey("should fail on unparseable lines by default", func() {
+			_, err := r.Next()
+			So(err, ShouldNotBeNil)
+		})
+		Convey("should return unparseable lines as raw entries in lenient mode", func() {
+			r.Lenient = true
+			var msgs []string
+			for {
+				e, err := r.Next()
+				if err == io.EOF {
+					break
+				}
+				So(err, ShouldBeNil)
+				msgs = append(msgs, e.Message())
+				if e.Time.IsZero() {
+					So(e.Line, ShouldEqual, -1)
+					So(e.Level, ShouldEqual, InfoLevel)
+				}
+			}
+			So(msgs, ShouldResemble, []string{
+				"    orphaned continuation",
+				"first",
+				"garbage line",
+				"second\ncontinued",
+			})
+		})
+	})
+}
+
+func TestFilteringLogReader(t *testing.T) {
+	logContent := `
+    leftover continuation from a previous entry
+I0101 00:00:00.000Z std_logger_test.go:00 (Flow=f1): Creating 20 blocks
+I0101 00:00:00.000Z std_logger_test.go:00 (Block=addFoo): Here's some JSON:
+    {
+      "A": "some val"
+    }
+I0101 00:00:00.000Z std_logger_test.go:00 (Flow=f1): Here's more:
+    Err:<nil>
+E0101 00:00:00.000Z std_logger_test.go:00 (Block=addFoo): Computing 5 things`[1:]
+
+	read := func(re string) string {
+		out, err := ioutil.ReadAll(NewFilteringLogReader(strings.NewReader(logContent), regexp.MustCompile(re)))
+		So(err, ShouldBeNil)
+		return string(out)
+	}
+
+	Convey("FilteringLogReader", t, func() {
+		Convey("should pass through whole matching entries", func() {
+			So(read(`^Flow=f1$`), ShouldEqual, `
+I0101 00:00:00.000Z std_logger_test.go:00 (Flow=f1): Creating 20 blocks
+I0101 00:00:00.000Z std_logger_test.go:00 (Flow=f1): Here's more:
+    Err:<nil>
+`[1:])
+		})
+		Convey("should include a final entry without a trailing newline", func() {
+			So(read(`addFoo`), ShouldEqual, `
+I0101 00:00:00.000Z std_logger_test.go:00 (Block=addFoo): Here's some JSON:
+    {
+      "A": "some val"
+    }
+E0101 00:00:00.000Z std_logger_test.go:00 (Block=addFoo): Computing 5 things`[1:])
+		})
+		Convey("should yield nothing when no context matches", func() {
+			So(read(`Flow=f2`), ShouldEqual, "")
+		})
+		Convey("should work with small read buffers", func() {
+			r := NewFilteringLogReader(strings.NewReader(logContent), regexp.MustCompile(`f1`))
+			var out []byte
+			buf := make([]byte, 3)
+			for {
+				n, err := r.Read(buf)
+				out = append(out, buf[:n]...)
+				if err == io.EOF {
+					break
+				}
+				So(err, ShouldBeNil)
+			}
+			So(string(out), ShouldEqual, read(`f1`))
+		})
+	})
+}
+
+func TestLogfmtReader(t *testing.T) {
+	logContent := `
+level=info ts=2016-01-01T00:00:00.000Z caller=std_logger_test.go:7 context="Flow=f1" msg="Creating 20 blocks" n=20
+
+level=error ts=2016-01-01T00:00:00.5+01:00 msg="a\nb" level=shadowed
+msg=bare caller=main.go`[1:]
+
+	Convey("LogfmtReader should read each entry", t, func() {
+		r := NewLogfmtReader(strings.NewReader(logContent))
+		entry, err := r.Next()
+		So(err, ShouldBeNil)
+		So(entry, ShouldResemble, Entry{
+			Level:   InfoLevel,
+			Time:    time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC),
+			File:    "std_logger_test.go",
+			Line:    7,
+			Context: "Flow=f1",
+			Fmt:     kNO_FORMAT,
+			Args:    args("Creating 20 blocks"),
+			Fields:  Fields{{"n", "20"}},
+		})
+
+		entry, err = r.Next()
+		So(err, ShouldBeNil)
+		So(entry.Level, ShouldEqual, ErrorLevel)
+		So(entry.Time.Equal(time.Date(2015, 12, 31, 23, 0, 0, 5e8, time.UTC)), ShouldBeTrue)
+		So(entry.File, ShouldEqual, "")
+		So(entry.Line, ShouldEqual, -1)
+		So(entry.Args, ShouldResemble, args("a\nb"))
+		So(entry.Fields, ShouldResemble, Fields{{"level", "shadowed"}})
+
+		entry, err = r.Next()
+		So(err, ShouldBeNil)
+		So(entry, ShouldResemble, Entry{Level: InfoLevel, File: "main.go", Line: -1, Fmt: kNO_FORMAT, Args: args("bare")})
+
+		_, err = r.Next()
+		So(err, ShouldEqual, io.EOF)
+	})
+
+	Convey("LogfmtReader should reject malformed lines", t, func() {
+		for _, line := range []string{`msg="unterminated`, "level=loud", "ts=yesterday", "caller=a.go:x", "novalue"} {
+			_, err := NewLogfmtReader(strings.NewReader(line)).Next()
+			So(err, ShouldNotBeNil)
+		}
+
+		Convey("unless lenient", func() {
+			r := NewLogfmtReader(strings.NewReader("novalue\nmsg=ok\n"))
+			r.Lenient = true
+			entry, err := r.Next()
+			So(err, ShouldBeNil)
+			So(entry, ShouldResemble, rawEntry("novalue"))
+			entry, err = r.Next()
+			So(err, ShouldBeNil)
+			So(entry.Message(), ShouldEqual, "ok")
+		})
+	})
 }
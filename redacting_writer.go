@@ -0,0 +1,192 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// RedactedText replaces redacted values in log output.
+const RedactedText = "[REDACTED]"
+
+// Redacted wraps a value that must never appear in logs.  It formats as
+// RedactedText with every verb, including %+v and %#v, and in JSON, so it is
+// safe to pass to any Logger method or as a field value:
+//
+//	log.Debugf("Connecting with %v", logging.Redacted{password})
+//
+// It is not hidden when it is an unexported field of a struct that is logged,
+// since fmt then prints it by reflection, as in "{Value:hunter2}", without
+// calling its methods.
+type Redacted struct{ Value interface{} }
+
+func (Redacted) String() string                { return RedactedText }
+func (Redacted) GoString() string              { return RedactedText }
+func (Redacted) Format(f fmt.State, verb rune) { io.WriteString(f, RedactedText) }
+func (Redacted) MarshalJSON() ([]byte, error)  { return []byte(`"` + RedactedText + `"`), nil }
+func (Redacted) MarshalText() ([]byte, error)  { return []byte(RedactedText), nil }
+
+// RedactionRules configures a RedactingWriter.
+type RedactionRules struct {
+	// Patterns match secrets in messages and field values.  If a pattern has
+	// capturing groups, only the text of the groups is redacted, otherwise the
+	// whole match is.
+	Patterns []*regexp.Regexp
+	// Fields lists the names of sensitive fields, whose values are always
+	// redacted.  Names are compared case-insensitively, and against the last
+	// dot-separated part of field keys, so "password" also matches
+	// "user.Password".  Values following these names in messages, as in
+	// "password=hunter2" or "Password:hunter2", are redacted too.
+	Fields []string
+}
+
+// DefaultRedactionRules redacts bearer and basic credentials, AWS access keys,
+// email addresses, and fields with common secret names.
+var DefaultRedactionRules = RedactionRules{
+	Patterns: []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\b(?:bearer|basic)\s+([A-Za-z0-9\-._~+/]{8,}=*)`),
+		regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+		regexp.MustCompile(`\b[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}\b`),
+	},
+	Fields: []string{
+		"password", "passwd", "secret", "token", "access_token", "refresh_token",
+		"api_key", "apikey", "authorization", "cookie", "set-cookie",
+		"aws_secret_access_key", "private_key",
+	},
+}
+
+// RedactingWriter is a Writer that scrubs secrets from entries before passing
+// them on to another Writer.  Messages, arguments and field values that contain
+// a match of the rules are replaced by redacted strings; entries without
+// secrets are passed on unchanged.  Values other than strings are checked as
+// formatted with %+v, so that the names of struct fields are matched too.  If
+// any argument or the message has secrets, the entry is passed on with the
+// redacted message as its only argument, so that Writers that log raw
+// arguments, such as JSONWriter, cannot reveal them.
+type RedactingWriter struct {
+	writer   Writer
+	patterns []*regexp.Regexp
+	fields   map[string]bool
+}
+
+// NewRedactingWriter returns a RedactingWriter that applies rules to entries
+// written to w.
+func NewRedactingWriter(w Writer, rules RedactionRules) *RedactingWriter {
+	r := &RedactingWriter{writer: w, patterns: rules.Patterns, fields: map[string]bool{}}
+	if len(rules.Fields) > 0 {
+		names := make([]string, len(rules.Fields))
+		for i, name := range rules.Fields {
+			names[i] = regexp.QuoteMeta(name)
+			r.fields[strings.ToLower(name)] = true
+		}
+		// Matches e.g. password=x, "password": "x" and Password:x from %+v.
+		byName := regexp.MustCompile(`(?i)\b(?:` + strings.Join(names, "|") +
+			`)["']?\s*[:=]\s*\[?["']?([^\s,;&"'\]}]+)`)
+		r.patterns = append(r.patterns[:len(r.patterns):len(r.patterns)], byName)
+	}
+	return r
+}
+
+func (r *RedactingWriter) Write(e Entry) error {
+	var args []interface{}
+	for i, arg := range e.Args {
+		if s, ok := r.redactValue(arg); ok {
+			if args == nil {
+				args = append([]interface{}(nil), e.Args...)
+			}
+			args[i] = s
+		}
+	}
+	if args != nil {
+		e.Args = args
+	}
+	if msg, ok := r.redact(e.Message()); ok || args != nil {
+		e.Fmt, e.Args = kNO_FORMAT, []interface{}{msg}
+	}
+	copied := false
+	for i, f := range e.Fields {
+		var value interface{}
+		if r.sensitiveField(f.Key) {
+			value = Redacted{} // drop the secret itself
+		} else if s, ok := r.redactValue(f.Value); ok {
+			value = s
+		} else {
+			continue
+		}
+		if !copied {
+			// The fields may be shared with the logger, so copy them.
+			e.Fields, copied = append(Fields(nil), e.Fields...), true
+		}
+		e.Fields[i].Value = value
+	}
+	return r.writer.Write(e)
+}
+
+func (r *RedactingWriter) sensitiveField(key string) bool {
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		key = key[i+1:]
+	}
+	return r.fields[strings.ToLower(key)]
+}
+
+// fieldText returns the text that a field value would be logged as.
+func fieldText(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case Redacted:
+		return RedactedText
+	}
+	return fmt.Sprint(v)
+}
+
+// redactValue returns the text of v with all secrets replaced, and whether there
+// were any.  Values other than strings are formatted with %+v.
+func (r *RedactingWriter) redactValue(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return r.redact(v)
+	case Redacted:
+		return RedactedText, false
+	}
+	return r.redact(fmt.Sprintf("%+v", v))
+}
+
+// redact returns s with all secrets replaced, and whether there were any.
+func (r *RedactingWriter) redact(s string) (string, bool) {
+	changed := false
+	for _, p := range r.patterns {
+		replaced := false
+		matches := p.FindAllStringSubmatchIndex(s, -1)
+		if matches == nil {
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, m := range matches {
+			spans := m[:2]
+			if len(m) > 2 {
+				spans = m[2:] // only the groups
+			}
+			for i := 0; i < len(spans); i += 2 {
+				start, end := spans[i], spans[i+1]
+				if start < last || start == end {
+					continue // unmatched or overlapping group
+				}
+				if strings.HasPrefix(s[start:], RedactedText) ||
+					(start > 0 && strings.HasPrefix(s[start-1:], RedactedText)) {
+					continue // already redacted
+				}
+				b.WriteString(s[last:start])
+				b.WriteString(RedactedText)
+				last, replaced = end, true
+			}
+		}
+		if replaced {
+			b.WriteString(s[last:])
+			s, changed = b.String(), true
+		}
+	}
+	return s, changed
+}
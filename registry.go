@@ -0,0 +1,211 @@
+package logging
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registry is a tree of named StdLoggers.  Names are slash-separated paths such
+// as "flow/f1/block/addFoo", and each logger's Context is its parent's context
+// followed by a slash and the last element of its name, so the top-level
+// "flow" logger of a registry whose root has no context has context "flow" and
+// its descendant above has context "flow/f1/block/addFoo".
+//
+// A logger's level is that of the nearest ancestor, itself included, whose
+// level was set with SetLevel, or else that of the root.  Levels should be
+// changed through the registry rather than by calling SetLogLevel on its
+// loggers, which would be undone by the next change to an ancestor.  Registry
+// implements LevelSet, so it can be served by a LevelHandler.
+type Registry struct {
+	mutex sync.RWMutex
+	root  *registryNode
+	nodes map[string]*registryNode
+}
+
+type registryNode struct {
+	name     string
+	logger   *StdLogger
+	children map[string]*registryNode
+	// level is the level set for this node with SetLevel, or 0 to inherit.
+	level Level
+}
+
+// DefaultRegistry is the registry used by GetLogger.  Its root logs text to
+// stderr at all levels.
+var DefaultRegistry = NewRegistry(&StdLogger{Writer: &TextWriter{Writer: os.Stderr}, MinLevel: TraceLevel})
+
+// GetLogger returns the named logger from DefaultRegistry.
+func GetLogger(name string) *StdLogger { return DefaultRegistry.GetLogger(name) }
+
+// NewRegistry returns a registry whose loggers share root's Writer, Fields,
+// VModule, NoCaller and CallerSkip settings, and inherit root's context and
+// level.  Changing root's level with SetLevel("", level) applies to every
+// logger without a level of its own.  If root has no level, it is set to
+// InfoLevel.
+func NewRegistry(root *StdLogger) *Registry {
+	if root.LogLevel() < TraceLevel {
+		root.SetLogLevel(InfoLevel)
+	}
+	node := &registryNode{logger: root, level: root.LogLevel()}
+	return &Registry{root: node, nodes: map[string]*registryNode{"": node}}
+}
+
+// GetLogger returns the logger with the given name, creating it and any missing
+// ancestors if necessary.  The same name always yields the same logger.  The
+// empty name refers to the root.
+func (r *Registry) GetLogger(name string) *StdLogger {
+	name = cleanLoggerName(name)
+	r.mutex.RLock()
+	node := r.nodes[name]
+	r.mutex.RUnlock()
+	if node != nil {
+		return node.logger
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.node(name).logger
+}
+
+// node returns the named node, creating it if necessary.  r.mutex must be held
+// for writing.
+func (r *Registry) node(name string) *registryNode {
+	if node := r.nodes[name]; node != nil {
+		return node
+	}
+	parent, elem := r.root, name
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		parent, elem = r.node(name[:i]), name[i+1:]
+	}
+	context := elem
+	if parent.logger.Context != "" {
+		context = parent.logger.Context + "/" + elem
+	}
+	node := &registryNode{
+		name: name,
+		logger: &StdLogger{
+			Context:    context,
+			Writer:     parent.logger.Writer,
+			MinLevel:   parent.logger.LogLevel(),
+			Fields:     parent.logger.Fields,
+			VModule:    parent.logger.VModule,
+			NoCaller:   parent.logger.NoCaller,
+			CallerSkip: parent.logger.CallerSkip,
+		},
+	}
+	if parent.children == nil {
+		parent.children = map[string]*registryNode{}
+	}
+	parent.children[elem] = node
+	r.nodes[name] = node
+	return node
+}
+
+// SetLevel sets the level of the named logger and of all its descendants that
+// do not have a level of their own.  The logger is created if it does not exist
+// yet, so that loggers created under it later start at the new level.
+func (r *Registry) SetLevel(name string, level Level) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	node := r.node(cleanLoggerName(name))
+	node.level = level
+	node.propagate(level)
+	return nil
+}
+
+// HasLevel reports whether the named logger has a level of its own, set with
+// SetLevel.  The root always has one.
+func (r *Registry) HasLevel(name string) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	node := r.nodes[cleanLoggerName(name)]
+	return node != nil && node.level != 0
+}
+
+// ClearLevel makes the named logger inherit its level again.  It has no effect
+// on the root or on loggers that do not exist.
+func (r *Registry) ClearLevel(name string) {
+	name = cleanLoggerName(name)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	node := r.nodes[name]
+	if node == nil || node == r.root {
+		return
+	}
+	node.level = 0
+	node.propagate(r.inheritedLevel(name))
+}
+
+// inheritedLevel returns the level that the named node inherits from its
+// ancestors.  r.mutex must be held.
+func (r *Registry) inheritedLevel(name string) Level {
+	for name != "" {
+		i := strings.LastIndex(name, "/")
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+		if level := r.nodes[name].level; level != 0 {
+			return level
+		}
+	}
+	return r.root.level
+}
+
+// propagate sets the level of n's logger, and of the loggers of all its
+// descendants that inherit it, to level.
+func (n *registryNode) propagate(level Level) {
+	n.logger.SetLogLevel(level)
+	for _, child := range n.children {
+		if child.level == 0 {
+			child.propagate(level)
+		}
+	}
+}
+
+// Levels returns the level of every logger in the registry except the root.
+func (r *Registry) Levels() map[string]Level {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	levels := make(map[string]Level, len(r.nodes))
+	for name, node := range r.nodes {
+		if node != r.root {
+			levels[name] = node.logger.LogLevel()
+		}
+	}
+	return levels
+}
+
+// Names returns the names of all loggers in the registry except the root, in
+// sorted order.
+func (r *Registry) Names() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	names := make([]string, 0, len(r.nodes))
+	for name, node := range r.nodes {
+		if node != r.root {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+var _ LevelSet = &Registry{}
+
+// cleanLoggerName removes empty elements from a slash-separated logger name.
+func cleanLoggerName(name string) string {
+	if !strings.Contains(name, "//") && !strings.HasPrefix(name, "/") && !strings.HasSuffix(name, "/") {
+		return name
+	}
+	elems := strings.Split(name, "/")
+	out := elems[:0]
+	for _, elem := range elems {
+		if elem != "" {
+			out = append(out, elem)
+		}
+	}
+	return strings.Join(out, "/")
+}
@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRegistry(t *testing.T) {
+	Convey("A Registry", t, func() {
+		var c captureWriter
+		r := NewRegistry(&StdLogger{Writer: &c, MinLevel: InfoLevel, Fields: Fields{{"pid", 7}}})
+
+		Convey("should derive contexts from the parent", func() {
+			block := r.GetLogger("flow/f1/block/addFoo")
+			So(block.Context, ShouldEqual, "flow/f1/block/addFoo")
+			So(r.GetLogger("/flow//f1/"), ShouldEqual, r.GetLogger("flow/f1"))
+			So(r.Names(), ShouldResemble, []string{"flow", "flow/f1", "flow/f1/block", "flow/f1/block/addFoo"})
+
+			block.Info("hi")
+			So(c.Context, ShouldEqual, "flow/f1/block/addFoo")
+			So(c.Fields, ShouldResemble, Fields{{"pid", 7}})
+		})
+		Convey("should prefix the root's context", func() {
+			r := NewRegistry(&StdLogger{Context: "srv", Writer: &c, MinLevel: InfoLevel})
+			So(r.GetLogger("flow/f1").Context, ShouldEqual, "srv/flow/f1")
+			So(r.GetLogger(""), ShouldEqual, r.root.logger)
+		})
+		Convey("should share the root's caller skip", func() {
+			r := NewRegistry(&StdLogger{Writer: &c, MinLevel: InfoLevel, CallerSkip: 1})
+			So(r.GetLogger("flow/f1").CallerSkip, ShouldEqual, 1)
+		})
+		Convey("should default a root without a level to InfoLevel", func() {
+			r := NewRegistry(&StdLogger{Writer: &c})
+			So(r.GetLogger("flow").LogLevel(), ShouldEqual, InfoLevel)
+			So(r.Levels()["flow"], ShouldEqual, InfoLevel)
+		})
+		Convey("should inherit levels from the nearest configured ancestor", func() {
+			block := r.GetLogger("flow/f1/block/addFoo")
+			other := r.GetLogger("flow/f2")
+			So(block.LogLevel(), ShouldEqual, InfoLevel)
+
+			So(r.SetLevel("flow/f1", DebugLevel), ShouldBeNil)
+			So(block.LogLevel(), ShouldEqual, DebugLevel)
+			So(r.GetLogger("flow/f1/block/new").LogLevel(), ShouldEqual, DebugLevel)
+			So(other.LogLevel(), ShouldEqual, InfoLevel)
+
+			r.SetLevel("flow/f1/block/addFoo", TraceLevel)
+			r.SetLevel("flow", ErrorLevel)
+			So(block.LogLevel(), ShouldEqual, TraceLevel)
+			So(r.GetLogger("flow/f1/block").LogLevel(), ShouldEqual, DebugLevel)
+			So(other.LogLevel(), ShouldEqual, ErrorLevel)
+
+			r.ClearLevel("flow/f1")
+			So(r.GetLogger("flow/f1/block").LogLevel(), ShouldEqual, ErrorLevel)
+			So(block.LogLevel(), ShouldEqual, TraceLevel)
+			r.ClearLevel("flow")
+			So(other.LogLevel(), ShouldEqual, InfoLevel)
+		})
+		Convey("should apply root level changes", func() {
+			flow := r.GetLogger("flow")
+			r.SetLevel("", WarnLevel)
+			So(flow.LogLevel(), ShouldEqual, WarnLevel)
+			r.ClearLevel("")
+			So(r.GetLogger("").LogLevel(), ShouldEqual, WarnLevel)
+		})
+		Convey("should set levels before loggers exist", func() {
+			r.SetLevel("flow/f9", TraceLevel)
+			So(r.GetLogger("flow/f9/block/x").LogLevel(), ShouldEqual, TraceLevel)
+		})
+		Convey("should be served by a LevelHandler", func() {
+			block := r.GetLogger("flow/f1/block/addFoo")
+			h := NewLevelHandler(r)
+			So(serveLevels(h, "PUT", "/flow/f1", "", "trace").Code, ShouldEqual, http.StatusOK)
+			So(block.LogLevel(), ShouldEqual, TraceLevel)
+			So(serveLevels(h, "GET", "/flow/f1/block", "", "").Body.String(), ShouldEqual, "flow/f1/block trace\n")
+		})
+		Convey("should let a LevelHandler restore inherited levels", func() {
+			block := r.GetLogger("flow/f1/block")
+			h := NewLevelHandler(r)
+			serveLevels(h, "PUT", "/flow/f1/block?revert_after=20ms", "", "trace")
+			So(block.LogLevel(), ShouldEqual, TraceLevel)
+			for i := 0; i < 100 && r.HasLevel("flow/f1/block"); i++ {
+				time.Sleep(10 * time.Millisecond)
+			}
+			So(r.HasLevel("flow/f1/block"), ShouldBeFalse)
+			// The block follows its parent again rather than staying at the
+			// level it inherited before the change.
+			r.SetLevel("flow", WarnLevel)
+			So(block.LogLevel(), ShouldEqual, WarnLevel)
+		})
+	})
+}
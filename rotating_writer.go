@@ -0,0 +1,344 @@
+package logging
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// RotatingFileWriter is an io.Writer that appends to the file at Path and rolls
+// it over once it would grow beyond MaxSize bytes or when a multiple of
+// Interval passes.  It is intended as the destination of a TextWriter or
+// JSONWriter: each call to Write is kept whole within a single file, and those
+// writers emit each entry, including all of its continuation lines, in a single
+// call.  For example:
+//
+//	w := &RotatingFileWriter{Path: "/var/log/app.log", MaxSize: 100 << 20, MaxBackups: 5}
+//	defer w.Close()
+//	logger := NewTextLogger(w, "app", InfoLevel)
+//
+// Rolled files are renamed to Path.1, Path.2, ... with Path.1 the most recent.
+// Only the newest MaxBackups files are kept.  If Compress is set, rolled files
+// are gzipped in the background to Path.1.gz, Path.2.gz, ...; until then, the
+// rolled file is kept as Path.rolling.N, and the backups are shifted after
+// earlier compressions finish, so writes never wait for compression.  Files
+// left as Path.rolling.N by a process that exited before compressing them are
+// added to the backups, oldest first, at the first rotation.
+//
+// Interval boundaries are computed in UTC, so an Interval of 24 hours rolls the
+// file at UTC midnight.  An empty file is not rolled at interval boundaries.
+// The file is opened on first Write.  The zero values of
+// MaxSize and Interval disable the corresponding rotation.
+type RotatingFileWriter struct {
+	Path       string
+	MaxSize    int64
+	Interval   time.Duration
+	MaxBackups int
+	Compress   bool
+
+	mutex       sync.Mutex
+	file        *os.File
+	size        int64
+	deadline    time.Time
+	compressing sync.WaitGroup
+	lastRoll    chan struct{} // closed when the latest background roll is done
+	rolls       int           // highest N of the Path.rolling.N files
+	scanned     bool          // whether leftover Path.rolling.N files were looked for
+	closed      bool
+
+	now func() time.Time // overridden in tests
+}
+
+var errRotatingFileWriterClosed = errors.New("RotatingFileWriter is closed")
+
+func (w *RotatingFileWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		return 0, errRotatingFileWriterClosed
+	}
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	if w.needsRotation(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate rolls the current file over immediately.
+func (w *RotatingFileWriter) Rotate() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.closed {
+		return errRotatingFileWriterClosed
+	}
+	return w.rotate()
+}
+
+// Reopen closes and reopens the file at Path without rotating it.  This lets an
+// external tool such as logrotate move the file away.
+func (w *RotatingFileWriter) Reopen() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.closed {
+		return errRotatingFileWriterClosed
+	}
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+// ReopenOnSIGHUP calls Reopen whenever the process receives SIGHUP, until the
+// returned function is called.  Reopen errors are reported on stderr.
+func (w *RotatingFileWriter) ReopenOnSIGHUP() (stop func()) {
+	sigs := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigs, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-sigs:
+				if err := w.Reopen(); err != nil {
+					fmt.Fprintf(os.Stderr, "Log reopen failed: %v\n", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sigs)
+		close(done)
+	}
+}
+
+// Close closes the file and waits for any pending compression to finish.
+// Subsequent writes fail.
+func (w *RotatingFileWriter) Close() error {
+	w.mutex.Lock()
+	w.closed = true
+	err := w.closeFile()
+	w.mutex.Unlock()
+	w.compressing.Wait()
+	return err
+}
+
+func (w *RotatingFileWriter) clock() time.Time {
+	if w.now != nil {
+		return w.now()
+	}
+	return time.Now()
+}
+
+// needsRotation reports whether writing n more bytes requires a new file.  An
+// empty file is never rotated, so oversized writes still succeed and idle
+// intervals don't leave empty backups; its interval deadline is moved on
+// instead.
+func (w *RotatingFileWriter) needsRotation(n int64) bool {
+	if w.size == 0 {
+		if w.Interval > 0 && !w.clock().Before(w.deadline) {
+			w.setDeadline()
+		}
+		return false
+	}
+	if w.MaxSize > 0 && w.size+n > w.MaxSize {
+		return true
+	}
+	return w.Interval > 0 && !w.clock().Before(w.deadline)
+}
+
+// setDeadline sets the deadline to the end of the current interval.
+func (w *RotatingFileWriter) setDeadline() {
+	w.deadline = w.clock().UTC().Truncate(w.Interval).Add(w.Interval)
+}
+
+func (w *RotatingFileWriter) open() error {
+	f, err := os.OpenFile(w.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file, w.size = f, info.Size()
+	if w.Interval > 0 {
+		w.setDeadline()
+	}
+	return nil
+}
+
+func (w *RotatingFileWriter) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// rotate closes the current file, shifts the backups and opens a new file.
+func (w *RotatingFileWriter) rotate() error {
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+
+	if w.MaxBackups <= 0 {
+		if err := os.Remove(w.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return w.open()
+	}
+
+	if !w.Compress {
+		w.shiftBackups()
+		if err := os.Rename(w.Path, w.backupName(1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return w.open()
+	}
+
+	var leftovers []string
+	if !w.scanned {
+		w.scanned = true
+		leftovers = w.findLeftovers()
+	}
+
+	// Set the file aside, and shift and compress it in the background once
+	// the previous roll is done with the backups.
+	w.rolls++
+	rolled := fmt.Sprintf("%s.rolling.%d", w.Path, w.rolls)
+	if err := os.Rename(w.Path, rolled); err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		rolled = ""
+	}
+	prev, done := w.lastRoll, make(chan struct{})
+	w.lastRoll = done
+	w.compressing.Add(1)
+	go func() {
+		defer w.compressing.Done()
+		defer close(done)
+		if prev != nil {
+			<-prev
+		}
+		for _, name := range leftovers {
+			w.addBackup(name)
+		}
+		if rolled == "" {
+			w.shiftBackups()
+			return
+		}
+		w.addBackup(rolled)
+	}()
+	return w.open()
+}
+
+// addBackup shifts the backups and compresses the rolled file name to Path.1.gz,
+// or if that fails renames it to Path.1.
+func (w *RotatingFileWriter) addBackup(name string) {
+	w.shiftBackups()
+	if err := gzipFile(name, w.backupName(1)+".gz"); err != nil {
+		fmt.Fprintf(os.Stderr, "Log compression failed: %v\n", err)
+		os.Rename(name, w.backupName(1))
+	}
+}
+
+// findLeftovers returns the Path.rolling.N files left by earlier processes,
+// oldest first, and makes later rolled files numbered above them.
+func (w *RotatingFileWriter) findLeftovers() []string {
+	names, _ := filepath.Glob(w.Path + ".rolling.*")
+	type leftover struct {
+		name    string
+		modTime time.Time
+	}
+	var found []leftover
+	for _, name := range names {
+		n, err := strconv.Atoi(strings.TrimPrefix(name, w.Path+".rolling."))
+		info, serr := os.Stat(name)
+		if err != nil || serr != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if n > w.rolls {
+			w.rolls = n
+		}
+		found = append(found, leftover{name, info.ModTime()})
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].modTime.Before(found[j].modTime) })
+	leftovers := make([]string, len(found))
+	for i, f := range found {
+		leftovers[i] = f.name
+	}
+	return leftovers
+}
+
+// shiftBackups removes the oldest backup and renames the others to make room
+// for a new Path.1.
+func (w *RotatingFileWriter) shiftBackups() {
+	w.removeBackup(w.MaxBackups)
+	for i := w.MaxBackups - 1; i >= 1; i-- {
+		w.renameBackup(i, i+1)
+	}
+}
+
+func (w *RotatingFileWriter) backupName(i int) string { return fmt.Sprintf("%s.%d", w.Path, i) }
+
+func (w *RotatingFileWriter) removeBackup(i int) {
+	os.Remove(w.backupName(i))
+	os.Remove(w.backupName(i) + ".gz")
+}
+
+// renameBackup moves backup generation i to j, whether or not it was
+// compressed.
+func (w *RotatingFileWriter) renameBackup(i, j int) {
+	os.Rename(w.backupName(i), w.backupName(j))
+	os.Rename(w.backupName(i)+".gz", w.backupName(j)+".gz")
+}
+
+// gzipFile compresses name to dest and removes name.
+func gzipFile(name, dest string) error {
+	in, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	if _, err = io.Copy(gz, in); err == nil {
+		err = gz.Close()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dest)
+		return err
+	}
+	return os.Remove(name)
+}
@@ -0,0 +1,165 @@
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRotatingFileWriter(t *testing.T) {
+	Convey("RotatingFileWriter", t, func() {
+		dir, err := ioutil.TempDir("", "rotating_writer_test")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "app.log")
+		read := func(name string) string {
+			b, err := ioutil.ReadFile(name)
+			So(err, ShouldBeNil)
+			return string(b)
+		}
+		exists := func(name string) bool {
+			_, err := os.Stat(name)
+			return err == nil
+		}
+
+		Convey("should roll over at MaxSize without splitting entries", func() {
+			w := &RotatingFileWriter{Path: path, MaxSize: 100, MaxBackups: 2}
+			log := NewTextLogger(w, "ctx", TraceLevel)
+			log.Info("first\n" + strings.Repeat("x", 40))
+			log.Info("second\n" + strings.Repeat("y", 40))
+			log.Info("third")
+			So(w.Close(), ShouldBeNil)
+
+			So(read(path+".2"), ShouldContainSubstring, "first\n"+continuation+strings.Repeat("x", 40)+"\n")
+			So(read(path+".1"), ShouldContainSubstring, "second\n"+continuation+strings.Repeat("y", 40)+"\n")
+			So(read(path), ShouldContainSubstring, "third")
+			So(read(path), ShouldNotContainSubstring, "second")
+		})
+		Convey("should keep only MaxBackups generations", func() {
+			w := &RotatingFileWriter{Path: path, MaxBackups: 2}
+			for _, s := range []string{"a", "b", "c", "d"} {
+				w.Write([]byte(s))
+				So(w.Rotate(), ShouldBeNil)
+			}
+			So(w.Close(), ShouldBeNil)
+			So(read(path+".1"), ShouldEqual, "d")
+			So(read(path+".2"), ShouldEqual, "c")
+			So(exists(path+".3"), ShouldBeFalse)
+			So(read(path), ShouldEqual, "")
+		})
+		Convey("should compress rolled files", func() {
+			w := &RotatingFileWriter{Path: path, MaxBackups: 2, Compress: true}
+			for _, s := range []string{"a", "b", "c"} {
+				w.Write([]byte(s))
+				So(w.Rotate(), ShouldBeNil)
+			}
+			So(w.Close(), ShouldBeNil)
+			So(exists(path+".1"), ShouldBeFalse)
+			So(exists(path+".3.gz"), ShouldBeFalse)
+
+			f, err := os.Open(path + ".2.gz")
+			So(err, ShouldBeNil)
+			defer f.Close()
+			gz, err := gzip.NewReader(f)
+			So(err, ShouldBeNil)
+			b, err := ioutil.ReadAll(gz)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, "b")
+		})
+		Convey("should roll over at interval boundaries", func() {
+			now := time.Date(2016, 5, 23, 23, 59, 0, 0, time.UTC)
+			w := &RotatingFileWriter{Path: path, Interval: 24 * time.Hour, MaxBackups: 1}
+			w.now = func() time.Time { return now }
+			w.Write([]byte("before midnight"))
+			now = now.Add(30 * time.Second)
+			w.Write([]byte(" still"))
+			now = now.Add(30 * time.Second)
+			w.Write([]byte("after midnight"))
+			So(w.Close(), ShouldBeNil)
+			So(read(path+".1"), ShouldEqual, "before midnight still")
+			So(read(path), ShouldEqual, "after midnight")
+		})
+		Convey("should add compressed files left by a crash to the backups", func() {
+			So(ioutil.WriteFile(path+".rolling.2", []byte("older"), 0644), ShouldBeNil)
+			So(ioutil.WriteFile(path+".rolling.1", []byte("old"), 0644), ShouldBeNil)
+			later := time.Now().Add(time.Second)
+			So(os.Chtimes(path+".rolling.1", later, later), ShouldBeNil)
+			w := &RotatingFileWriter{Path: path, MaxBackups: 3, Compress: true}
+			w.Write([]byte("new"))
+			So(w.Rotate(), ShouldBeNil)
+			So(w.Close(), ShouldBeNil)
+			for i, s := range []string{"new", "old", "older"} {
+				f, err := os.Open(fmt.Sprintf("%s.%d.gz", path, i+1))
+				So(err, ShouldBeNil)
+				gz, err := gzip.NewReader(f)
+				So(err, ShouldBeNil)
+				b, err := ioutil.ReadAll(gz)
+				f.Close()
+				So(err, ShouldBeNil)
+				So(string(b), ShouldEqual, s)
+			}
+			matches, _ := filepath.Glob(path + ".rolling.*")
+			So(matches, ShouldBeEmpty)
+		})
+		Convey("should not roll empty files at interval boundaries", func() {
+			now := time.Date(2016, 5, 23, 12, 0, 0, 0, time.UTC)
+			w := &RotatingFileWriter{Path: path, Interval: time.Hour, MaxBackups: 2}
+			w.now = func() time.Time { return now }
+			w.Write([]byte("noon"))
+			now = now.Add(time.Hour)
+			So(w.Rotate(), ShouldBeNil)
+			now = now.Add(3 * time.Hour)
+			w.Write([]byte("afternoon"))
+			now = now.Add(30 * time.Minute)
+			w.Write([]byte(" still"))
+			So(w.Close(), ShouldBeNil)
+			So(read(path+".1"), ShouldEqual, "noon")
+			So(exists(path+".2"), ShouldBeFalse)
+			So(read(path), ShouldEqual, "afternoon still")
+		})
+		Convey("should keep compressed generations in order", func() {
+			w := &RotatingFileWriter{Path: path, MaxBackups: 3, Compress: true}
+			for _, s := range []string{"a", "b", "c", "d"} {
+				w.Write([]byte(s))
+				So(w.Rotate(), ShouldBeNil)
+			}
+			So(w.Close(), ShouldBeNil)
+			for i, s := range []string{"d", "c", "b"} {
+				f, err := os.Open(fmt.Sprintf("%s.%d.gz", path, i+1))
+				So(err, ShouldBeNil)
+				gz, err := gzip.NewReader(f)
+				So(err, ShouldBeNil)
+				b, err := ioutil.ReadAll(gz)
+				f.Close()
+				So(err, ShouldBeNil)
+				So(string(b), ShouldEqual, s)
+			}
+			matches, _ := filepath.Glob(path + ".rolling.*")
+			So(matches, ShouldBeEmpty)
+		})
+		Convey("should reopen the file after it is moved away", func() {
+			w := &RotatingFileWriter{Path: path}
+			w.Write([]byte("old"))
+			So(os.Rename(path, path+".moved"), ShouldBeNil)
+			So(w.Reopen(), ShouldBeNil)
+			w.Write([]byte("new"))
+			So(w.Close(), ShouldBeNil)
+			So(read(path+".moved"), ShouldEqual, "old")
+			So(read(path), ShouldEqual, "new")
+		})
+		Convey("should fail writes after Close", func() {
+			w := &RotatingFileWriter{Path: path}
+			So(w.Close(), ShouldBeNil)
+			_, err := w.Write([]byte("x"))
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
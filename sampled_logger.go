@@ -0,0 +1,315 @@
+package logging
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// SamplePolicy configures a SampledLogger.
+type SamplePolicy struct {
+	// Burst is the number of entries logged for each key in each Interval
+	// before sampling starts.
+	Burst int
+	// Every logs every Mth entry for a key once its burst is used up.  If it is
+	// zero, all further entries in the interval are suppressed.
+	Every int
+	// Interval is the length of the window over which entries are counted.  It
+	// must be positive.
+	Interval time.Duration
+	// ByFormat keys entries by their format string instead of by call site.
+	// Entries logged without a format, with Info rather than Infof, are still
+	// keyed by call site.
+	ByFormat bool
+}
+
+// SampledLogger wraps a Logger to limit the rate of similar entries, so that a
+// tight retry loop cannot fill the disk.  Entries are grouped by call site, or
+// by format string, and each group may log Burst entries per Interval, then
+// every Every-th entry.  When an interval in which entries were suppressed is
+// over, a message saying how many is logged from a timer, at the level and
+// through the logger of the last of them, even if nothing else is logged.
+// Call Flush to report suppressed entries right away, for example on shutdown.
+//
+// Entries that the wrapped logger would not log are passed through without
+// being counted.  Loggers derived with With share their parent's counters.
+type SampledLogger struct {
+	Logger
+	policy  SamplePolicy
+	sampler *sampler
+	// callerSkip is added when finding call sites; see WithCallerSkip.
+	callerSkip int
+}
+
+// NewSampledLogger returns a SampledLogger that logs to l according to policy.
+// It panics if policy.Interval is not positive.
+func NewSampledLogger(l Logger, policy SamplePolicy) *SampledLogger {
+	if policy.Interval <= 0 {
+		panic(fmt.Errorf("Invalid SamplePolicy.Interval: %v", policy.Interval))
+	}
+	return &SampledLogger{Logger: l, policy: policy, sampler: &sampler{keys: map[interface{}]*sampleKey{}}}
+}
+
+var _ Logger = &SampledLogger{}
+
+// sampler holds the counters of a family of SampledLoggers.
+type sampler struct {
+	mutex     sync.Mutex
+	keys      map[interface{}]*sampleKey
+	lastSweep time.Time
+	// timer sweeps the keys once the earliest interval with suppressed entries
+	// is over.  It is set whenever a key has suppressed entries.  timerGen
+	// identifies it, so that a stopped timer that fired anyway does nothing.
+	timer    *time.Timer
+	timerGen uint64
+	now      func() time.Time // overridden in tests
+}
+
+type sampleKey struct {
+	start      time.Time // start of the current interval
+	count      int       // entries in the current interval
+	suppressed int       // entries suppressed in the current interval
+	level      Level     // level of the last suppressed entry
+	logger     Logger    // logger of the last suppressed entry
+	desc       string    // describes the key in summaries
+}
+
+// sampleSummary reports the entries suppressed for one key.
+type sampleSummary struct {
+	level  Level
+	logger Logger
+	msg    string
+}
+
+func (s *sampler) timeNow() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// allow reports whether an entry should be logged, and logs any summaries that
+// are due.  It must be called directly from the Logger methods so that the call
+// site is found without looking at more frames than necessary.
+func (l *SampledLogger) allow(level Level, fmtstr string) bool {
+	if !l.Logger.Enabled(level) {
+		return true // let the wrapped logger drop it
+	}
+	var key interface{}
+	if l.policy.ByFormat && fmtstr != kNO_FORMAT {
+		key = fmtstr
+	} else {
+		key = callSite(findCaller(2, l.callerSkip).pc) // skip allow and the method
+	}
+
+	s := l.sampler
+	s.mutex.Lock()
+	now := s.timeNow()
+	var summaries []sampleSummary
+	if now.Sub(s.lastSweep) >= l.policy.Interval {
+		summaries = s.sweep(now, l.policy.Interval)
+		s.lastSweep = now
+	}
+	k := s.keys[key]
+	if k == nil {
+		k = &sampleKey{start: now}
+		s.keys[key] = k
+	} else if now.Sub(k.start) >= l.policy.Interval {
+		if k.suppressed > 0 {
+			summaries = append(summaries, k.summary())
+		}
+		k.start, k.count, k.suppressed = now, 0, 0
+	}
+	k.count++
+	allowed := k.count <= l.policy.Burst ||
+		(l.policy.Every > 0 && (k.count-l.policy.Burst)%l.policy.Every == 0)
+	if !allowed {
+		if k.suppressed == 0 {
+			k.desc = describeSampleKey(key)
+		}
+		k.suppressed++
+		k.level, k.logger = level, l.Logger
+		if s.timer == nil {
+			s.startTimer(k.start.Add(l.policy.Interval).Sub(now), l.policy.Interval)
+		}
+	}
+	s.mutex.Unlock()
+
+	logSummaries(summaries)
+	return allowed
+}
+
+// startTimer arranges for the keys to be swept after d.  s.mutex must be held.
+func (s *sampler) startTimer(d, interval time.Duration) {
+	s.timerGen++
+	gen := s.timerGen
+	s.timer = time.AfterFunc(d, func() { s.timedSweep(gen, interval) })
+}
+
+// timedSweep logs the summaries of the keys whose interval has ended, and
+// restarts the timer for the remaining keys with suppressed entries.
+func (s *sampler) timedSweep(gen uint64, interval time.Duration) {
+	s.mutex.Lock()
+	if s.timer == nil || gen != s.timerGen {
+		s.mutex.Unlock()
+		return
+	}
+	now := s.timeNow()
+	summaries := s.sweep(now, interval)
+	s.lastSweep = now
+	s.timer = nil
+	var next time.Time
+	for _, k := range s.keys {
+		if k.suppressed > 0 && (next.IsZero() || k.start.Before(next)) {
+			next = k.start
+		}
+	}
+	if !next.IsZero() {
+		s.startTimer(next.Add(interval).Sub(now), interval)
+	}
+	s.mutex.Unlock()
+	logSummaries(summaries)
+}
+
+// sweep removes keys whose interval has ended, returning summaries for those
+// with suppressed entries.  s.mutex must be held.
+func (s *sampler) sweep(now time.Time, interval time.Duration) []sampleSummary {
+	var summaries []sampleSummary
+	for key, k := range s.keys {
+		if now.Sub(k.start) < interval {
+			continue
+		}
+		if k.suppressed > 0 {
+			summaries = append(summaries, k.summary())
+		}
+		delete(s.keys, key)
+	}
+	return summaries
+}
+
+func (k *sampleKey) summary() sampleSummary {
+	return sampleSummary{k.level, k.logger, fmt.Sprintf("Suppressed %d similar messages %s", k.suppressed, k.desc)}
+}
+
+func describeSampleKey(key interface{}) string {
+	if site, ok := key.(callSiteKey); ok {
+		return "from " + string(site)
+	}
+	return fmt.Sprintf("like %q", key)
+}
+
+// callSiteKey is the file:line of a call site.  It is a distinct type so that
+// it cannot collide with format strings as a key.
+type callSiteKey string
+
+// callSites caches the callSiteKey for each pc.  Keying on file and line
+// rather than pc treats inlined copies of a call as the same site.
+var callSites sync.Map
+
+func callSite(pc uintptr) callSiteKey {
+	if site, ok := callSites.Load(pc); ok {
+		return site.(callSiteKey)
+	}
+	site := callSiteKey("???")
+	if pc != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		site = callSiteKey(fmt.Sprintf("%s:%d", frame.File, frame.Line))
+	}
+	callSites.Store(pc, site)
+	return site
+}
+
+func logSummaries(summaries []sampleSummary) {
+	for _, s := range summaries {
+		logAtLevel(s.logger, s.level, s.msg)
+	}
+}
+
+// Flush logs summaries for all suppressed entries and resets the counters.
+func (l *SampledLogger) Flush() {
+	s := l.sampler
+	s.mutex.Lock()
+	var summaries []sampleSummary
+	for _, k := range s.keys {
+		if k.suppressed > 0 {
+			summaries = append(summaries, k.summary())
+		}
+	}
+	s.keys = map[interface{}]*sampleKey{}
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.mutex.Unlock()
+	logSummaries(summaries)
+}
+
+func (l *SampledLogger) Trace(vals ...interface{}) {
+	if l.allow(TraceLevel, kNO_FORMAT) {
+		l.Logger.Trace(vals...)
+	}
+}
+func (l *SampledLogger) Debug(vals ...interface{}) {
+	if l.allow(DebugLevel, kNO_FORMAT) {
+		l.Logger.Debug(vals...)
+	}
+}
+func (l *SampledLogger) Info(vals ...interface{}) {
+	if l.allow(InfoLevel, kNO_FORMAT) {
+		l.Logger.Info(vals...)
+	}
+}
+func (l *SampledLogger) Warn(vals ...interface{}) {
+	if l.allow(WarnLevel, kNO_FORMAT) {
+		l.Logger.Warn(vals...)
+	}
+}
+func (l *SampledLogger) Error(vals ...interface{}) {
+	if l.allow(ErrorLevel, kNO_FORMAT) {
+		l.Logger.Error(vals...)
+	}
+}
+
+func (l *SampledLogger) Tracef(fmt string, params ...interface{}) {
+	if l.allow(TraceLevel, fmt) {
+		l.Logger.Tracef(fmt, params...)
+	}
+}
+func (l *SampledLogger) Debugf(fmt string, params ...interface{}) {
+	if l.allow(DebugLevel, fmt) {
+		l.Logger.Debugf(fmt, params...)
+	}
+}
+func (l *SampledLogger) Infof(fmt string, params ...interface{}) {
+	if l.allow(InfoLevel, fmt) {
+		l.Logger.Infof(fmt, params...)
+	}
+}
+func (l *SampledLogger) Warnf(fmt string, params ...interface{}) {
+	if l.allow(WarnLevel, fmt) {
+		l.Logger.Warnf(fmt, params...)
+	}
+}
+func (l *SampledLogger) Errorf(fmt string, params ...interface{}) {
+	if l.allow(ErrorLevel, fmt) {
+		l.Logger.Errorf(fmt, params...)
+	}
+}
+
+// fatalf logs at FatalLevel without sampling: the process is about to exit.
+func (l *SampledLogger) fatalf(fmt string, params ...interface{}) {
+	logFatalf(l.Logger, fmt, params...)
+}
+
+// With returns a SampledLogger that wraps l's logger with the given key/value
+// pairs and shares l's counters.
+func (l *SampledLogger) With(keyvals ...interface{}) Logger {
+	return &SampledLogger{Logger: l.Logger.With(keyvals...), policy: l.policy, sampler: l.sampler, callerSkip: l.callerSkip}
+}
+
+// WithCallerSkip returns a logger that shares l's counters and, like the logger
+// it wraps, finds call sites n frames further up the stack.
+func (l *SampledLogger) WithCallerSkip(n int) Logger {
+	return &SampledLogger{Logger: WithCallerSkip(l.Logger, n), policy: l.policy, sampler: l.sampler, callerSkip: l.callerSkip + n}
+}
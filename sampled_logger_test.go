@@ -0,0 +1,115 @@
+package logging
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSampledLogger(t *testing.T) {
+	Convey("SampledLogger", t, func() {
+		mem := NewMemLogger()
+		now := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+		newLogger := func(policy SamplePolicy) *SampledLogger {
+			l := NewSampledLogger(mem, policy)
+			l.sampler.now = func() time.Time { return now }
+			return l
+		}
+		msgs := func() []string {
+			var out []string
+			for _, m := range mem.ExtractMsgs() {
+				out = append(out, m.Msg)
+			}
+			return out
+		}
+
+		Convey("should allow a burst per call site, then every Mth entry", func() {
+			l := newLogger(SamplePolicy{Burst: 2, Every: 3, Interval: time.Minute})
+			for i := 1; i <= 10; i++ {
+				l.Errorf("retry %d", i)
+			}
+			l.Info("elsewhere")
+			So(msgs(), ShouldResemble, []string{"retry 1", "retry 2", "retry 5", "retry 8", "elsewhere"})
+		})
+		Convey("should report suppressed entries after the interval", func() {
+			l := newLogger(SamplePolicy{Burst: 1, Interval: time.Minute})
+			for i := 0; i < 4; i++ {
+				l.Warn("flood")
+			}
+			So(msgs(), ShouldResemble, []string{"flood"})
+
+			now = now.Add(time.Minute)
+			l.Info("later")
+			entries := mem.ExtractEntries()
+			So(entries, ShouldHaveLength, 2)
+			So(entries[0].Level, ShouldEqual, WarnLevel)
+			So(entries[0].Message(), ShouldStartWith, "Suppressed 3 similar messages from ")
+			So(entries[0].Message(), ShouldContainSubstring, "sampled_logger_test.go:")
+			So(entries[1].Message(), ShouldEqual, "later")
+		})
+		Convey("should start a new burst in a new interval", func() {
+			l := newLogger(SamplePolicy{Burst: 1, Interval: time.Minute})
+			logIt := func() { l.Debugf("tick %d", 1) }
+			logIt()
+			logIt()
+			now = now.Add(time.Minute)
+			logIt()
+			got := msgs()
+			So(got, ShouldHaveLength, 3)
+			So(got[0], ShouldEqual, "tick 1")
+			So(got[1], ShouldStartWith, "Suppressed 1 similar messages from ")
+			So(got[2], ShouldEqual, "tick 1")
+		})
+		Convey("should key by format string", func() {
+			l := newLogger(SamplePolicy{Burst: 1, Interval: time.Minute, ByFormat: true})
+			l.Infof("conn %d failed", 1)
+			l.Infof("conn %d failed", 2)
+			l.With("k", "v").Infof("conn %d failed", 3)
+			l.Flush()
+			So(msgs(), ShouldResemble, []string{"conn 1 failed", `Suppressed 2 similar messages like "conn %d failed"`})
+		})
+		Convey("should pass through entries below the level", func() {
+			mem.SetLogLevel(InfoLevel)
+			l := newLogger(SamplePolicy{Burst: 1, Interval: time.Minute})
+			logIt := func(level Level) { logAtLevel(l, level, "x") }
+			logIt(DebugLevel)
+			logIt(DebugLevel)
+			logIt(InfoLevel)
+			So(msgs(), ShouldResemble, []string{"x"})
+		})
+		Convey("should wrap loggers of any kind", func() {
+			mem.SetLogLevel(InfoLevel)
+			l := NewSampledLogger(&CancellableLogger{Logger: NewTeeLogger(mem)}, SamplePolicy{Burst: 1, Interval: time.Minute})
+			logIt := func(level Level) { logAtLevel(l, level, "x") }
+			logIt(DebugLevel)
+			logIt(InfoLevel)
+			logIt(InfoLevel)
+			So(msgs(), ShouldResemble, []string{"x"})
+		})
+		Convey("should report suppressed entries when the interval ends", func() {
+			l := NewSampledLogger(mem, SamplePolicy{Burst: 1, Interval: 10 * time.Millisecond})
+			for i := 0; i < 3; i++ {
+				l.With("k", "v").Error("flood")
+			}
+			var entries []Entry
+			for i := 0; i < 100 && len(entries) < 2; i++ {
+				time.Sleep(10 * time.Millisecond)
+				entries = mem.Entries()
+			}
+			So(entries, ShouldHaveLength, 2)
+			So(entries[1].Level, ShouldEqual, ErrorLevel)
+			So(entries[1].Message(), ShouldStartWith, "Suppressed 2 similar messages from ")
+			So(entries[1].Fields, ShouldResemble, Fields{{"k", "v"}})
+		})
+		Convey("should reject intervals that are not positive", func() {
+			So(func() { NewSampledLogger(mem, SamplePolicy{Burst: 1}) }, ShouldPanic)
+		})
+		Convey("should report the wrapped call site", func() {
+			var c captureWriter
+			l := NewSampledLogger(&StdLogger{Writer: &c, MinLevel: TraceLevel}, SamplePolicy{Burst: 1, Interval: time.Minute})
+			l.Info("hi")
+			So(c.File, ShouldEndWith, "sampled_logger_test.go")
+		})
+	})
+}
@@ -0,0 +1,171 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// SlogContextKey is the slog attribute key that corresponds to the context
+// string of a StdLogger, in both directions.
+const SlogContextKey = "context"
+
+// SlogLevel converts l to the corresponding slog level.  Trace and Fatal,
+// which slog does not define, map to four below slog.LevelDebug and four above
+// slog.LevelError respectively.
+func SlogLevel(l Level) slog.Level {
+	switch l {
+	case TraceLevel:
+		return slog.LevelDebug - 4
+	case DebugLevel:
+		return slog.LevelDebug
+	case InfoLevel:
+		return slog.LevelInfo
+	case WarnLevel:
+		return slog.LevelWarn
+	case ErrorLevel:
+		return slog.LevelError
+	case FatalLevel:
+		return slog.LevelError + 4
+	}
+	panic(fmt.Errorf("No such log level: %d", l))
+}
+
+// LevelFromSlog converts a slog level to the highest Level that does not
+// exceed it, e.g. slog.LevelInfo+2 becomes InfoLevel.
+func LevelFromSlog(l slog.Level) Level {
+	switch {
+	case l < slog.LevelDebug:
+		return TraceLevel
+	case l < slog.LevelInfo:
+		return DebugLevel
+	case l < slog.LevelWarn:
+		return InfoLevel
+	case l < slog.LevelError:
+		return WarnLevel
+	case l < slog.LevelError+4:
+		return ErrorLevel
+	}
+	return FatalLevel
+}
+
+// SlogHandler is a slog.Handler that logs through a Logger, so that code
+// written against log/slog shares the output of code using this package.
+// Attributes become entry fields, with group names prepended to the key and
+// separated by dots.  If the Logger is a *StdLogger, a top-level attribute named
+// SlogContextKey sets the entry's context instead; other Loggers have no way to
+// set it and receive it as a plain field.
+type SlogHandler struct {
+	logger Logger
+	// writer, context and fields are taken from logger if it is a *StdLogger.
+	// Records are then written directly, keeping their own file and line.
+	writer  Writer
+	context string
+	fields  Fields
+	prefix  string // group prefix for attribute keys
+}
+
+var _ slog.Handler = &SlogHandler{}
+
+// NewSlogHandler returns a slog.Handler that logs through l, at l's current
+// level.  If l is a *StdLogger, such as one returned by NewTextLogger, records
+// are written straight to its Writer with the file and line of the slog call.
+// Other Loggers receive the rendered message through their level methods, and
+// report the origin of the slog call as well, since they skip log/slog frames
+// when looking for it.
+func NewSlogHandler(l Logger) *SlogHandler {
+	h := &SlogHandler{logger: l}
+	if s, ok := l.(*StdLogger); ok {
+		h.writer, h.context, h.fields = s.Writer, s.Context, s.Fields
+	}
+	return h
+}
+
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.logger.Enabled(LevelFromSlog(level))
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	// Cap the fields so that appending never writes into h's backing array.
+	logContext, fields := h.context, h.fields[:len(h.fields):len(h.fields)]
+	r.Attrs(func(a slog.Attr) bool {
+		logContext, fields = h.addAttr(logContext, fields, h.prefix, a)
+		return true
+	})
+	level := LevelFromSlog(r.Level)
+
+	if h.writer == nil {
+		l := h.logger
+		if extra := fields[len(h.fields):]; len(extra) > 0 {
+			l = l.With(extra.keyvals()...)
+		}
+		logAtLevel(l, level, r.Message)
+		return nil
+	}
+
+	e := Entry{
+		Level:   level,
+		Time:    r.Time,
+		File:    "",
+		Line:    -1,
+		Context: logContext,
+		Fmt:     kNO_FORMAT,
+		Args:    []interface{}{r.Message},
+		Fields:  fields,
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		e.File, e.Line = frame.File, frame.Line
+	}
+	return h.writer.Write(e)
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.fields = h.fields[:len(h.fields):len(h.fields)]
+	for _, a := range attrs {
+		h2.context, h2.fields = h.addAttr(h2.context, h2.fields, h.prefix, a)
+	}
+	if h.writer == nil {
+		// Without a writer, derived fields can only reach the logger via With.
+		h2.logger = h.logger.With(h2.fields[len(h.fields):].keyvals()...)
+		h2.fields = h.fields
+	}
+	return &h2
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// addAttr adds a to the context or fields according to the rules described on
+// SlogHandler.
+func (h *SlogHandler) addAttr(logContext string, fields Fields, prefix string, a slog.Attr) (string, Fields) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return logContext, fields
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			logContext, fields = h.addAttr(logContext, fields, prefix, ga)
+		}
+		return logContext, fields
+	}
+	if prefix == "" && a.Key == SlogContextKey && h.writer != nil {
+		return a.Value.String(), fields
+	}
+	return logContext, append(fields, Field{prefix + a.Key, a.Value.Any()})
+}
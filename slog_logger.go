@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// SlogLogger is a Logger that sends its entries to a slog.Handler, so that code
+// written against this package can log through log/slog.  Each record carries
+// the caller's program counter, the rendered message, the logger's context
+// under SlogContextKey (if non-empty) and its fields as attributes.
+type SlogLogger struct {
+	Handler  slog.Handler
+	Context  string
+	MinLevel Level
+	// CallerSkip is the number of frames above the code that called into this
+	// package to skip when finding the program counter.  See WithCallerSkip.
+	CallerSkip int
+}
+
+// NewSlogLogger returns a Logger that logs to h.  Entries below minLevel, or
+// that h does not enable, are dropped.
+func NewSlogLogger(h slog.Handler, context string, minLevel Level) *SlogLogger {
+	return &SlogLogger{Handler: h, Context: context, MinLevel: minLevel}
+}
+
+func (l *SlogLogger) sloglogf(level Level, fmtstr string, vals ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	ctx := context.Background()
+	slevel := SlogLevel(level)
+
+	msg := Entry{Fmt: fmtstr, Args: vals}.Message()
+	// Skip sloglogf and the Info/Infof/... method.
+	r := slog.NewRecord(time.Now(), slevel, msg, findCaller(2, l.CallerSkip).pc)
+	if l.Context != "" {
+		r.AddAttrs(slog.String(SlogContextKey, l.Context))
+	}
+	l.Handler.Handle(ctx, r)
+}
+
+func (l *SlogLogger) Trace(vals ...interface{}) { l.sloglogf(TraceLevel, kNO_FORMAT, vals...) }
+func (l *SlogLogger) Debug(vals ...interface{}) { l.sloglogf(DebugLevel, kNO_FORMAT, vals...) }
+func (l *SlogLogger) Info(vals ...interface{})  { l.sloglogf(InfoLevel, kNO_FORMAT, vals...) }
+func (l *SlogLogger) Warn(vals ...interface{})  { l.sloglogf(WarnLevel, kNO_FORMAT, vals...) }
+func (l *SlogLogger) Error(vals ...interface{}) { l.sloglogf(ErrorLevel, kNO_FORMAT, vals...) }
+
+func (l *SlogLogger) Tracef(fmt string, params ...interface{}) {
+	l.sloglogf(TraceLevel, fmt, params...)
+}
+func (l *SlogLogger) Debugf(fmt string, params ...interface{}) {
+	l.sloglogf(DebugLevel, fmt, params...)
+}
+func (l *SlogLogger) Infof(fmt string, params ...interface{}) {
+	l.sloglogf(InfoLevel, fmt, params...)
+}
+func (l *SlogLogger) Warnf(fmt string, params ...interface{}) {
+	l.sloglogf(WarnLevel, fmt, params...)
+}
+func (l *SlogLogger) Errorf(fmt string, params ...interface{}) {
+	l.sloglogf(ErrorLevel, fmt, params...)
+}
+func (l *SlogLogger) fatalf(fmt string, params ...interface{}) {
+	l.sloglogf(FatalLevel, fmt, params...)
+}
+
+// Enabled reports whether level is at least the logger's level and enabled by
+// its handler.
+func (l *SlogLogger) Enabled(level Level) bool {
+	return level >= l.LogLevel() && l.Handler.Enabled(context.Background(), SlogLevel(level))
+}
+
+func (l *SlogLogger) LogLevel() Level { return Level(atomic.LoadInt32((*int32)(&l.MinLevel))) }
+func (l *SlogLogger) SetLogLevel(newLevel Level) {
+	atomic.StoreInt32((*int32)(&l.MinLevel), int32(newLevel))
+}
+
+// With returns a copy of the logger whose handler has the given key/value pairs
+// as additional attributes.
+func (l *SlogLogger) With(keyvals ...interface{}) Logger {
+	fields := Fields(nil).With(keyvals...)
+	attrs := make([]slog.Attr, len(fields))
+	for i, f := range fields {
+		attrs[i] = slog.Any(f.Key, f.Value)
+	}
+	return &SlogLogger{Handler: l.Handler.WithAttrs(attrs), Context: l.Context, MinLevel: l.LogLevel(), CallerSkip: l.CallerSkip}
+}
+
+// WithCallerSkip returns a copy of the logger that skips n more frames when
+// finding the program counter of records.
+func (l *SlogLogger) WithCallerSkip(n int) Logger {
+	return &SlogLogger{Handler: l.Handler, Context: l.Context, MinLevel: l.LogLevel(), CallerSkip: l.CallerSkip + n}
+}
+
+var _ Logger = &SlogLogger{}
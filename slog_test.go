@@ -0,0 +1,135 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSlogLevels(t *testing.T) {
+	Convey("Levels should round-trip through slog levels", t, func() {
+		for _, l := range allLevels {
+			So(LevelFromSlog(SlogLevel(l)), ShouldEqual, l)
+		}
+		So(SlogLevel(InfoLevel), ShouldEqual, slog.LevelInfo)
+		So(LevelFromSlog(slog.LevelInfo+2), ShouldEqual, InfoLevel)
+		So(LevelFromSlog(slog.LevelDebug-100), ShouldEqual, TraceLevel)
+	})
+}
+
+func TestSlogHandler(t *testing.T) {
+	Convey("A slog.Logger backed by a StdLogger", t, func() {
+		var c captureWriter
+		std := &StdLogger{Context: "Flow=f1", Writer: &c, MinLevel: DebugLevel, Fields: Fields{{"a", 1}}}
+		log := slog.New(NewSlogHandler(std))
+
+		Convey("should write entries with the slog call point", func() {
+			log.Info("hello", "b", 2)
+			So(c.Level, ShouldEqual, InfoLevel)
+			So(c.File, ShouldEndWith, "slog_test.go")
+			So(c.Line, ShouldBeGreaterThan, 0)
+			So(c.Context, ShouldEqual, "Flow=f1")
+			So(Entry(c).Message(), ShouldEqual, "hello")
+			So(c.Fields, ShouldResemble, Fields{{"a", 1}, {"b", int64(2)}})
+		})
+		Convey("should honour the logger's level", func() {
+			log.Debug("debug")
+			So(Entry(c).Message(), ShouldEqual, "debug")
+			std.SetLogLevel(WarnLevel)
+			log.Info("dropped")
+			So(Entry(c).Message(), ShouldEqual, "debug")
+		})
+		Convey("should map the context attribute and groups", func() {
+			log.With("context", "Block=addFoo").WithGroup("req").Warn("hi", "id", 7, slog.Group("user", "name", "bob"))
+			So(c.Level, ShouldEqual, WarnLevel)
+			So(c.Context, ShouldEqual, "Block=addFoo")
+			So(c.Fields, ShouldResemble, Fields{{"a", 1}, {"req.id", int64(7)}, {"req.user.name", "bob"}})
+		})
+		Convey("should not share fields between derived handlers", func() {
+			h := log.With("x", 1)
+			h.With("y", 2).Info("one")
+			h.With("z", 3).Info("two")
+			So(c.Fields, ShouldResemble, Fields{{"a", 1}, {"x", int64(1)}, {"z", int64(3)}})
+		})
+	})
+
+	Convey("A slog.Logger backed by another Logger", t, func() {
+		mem := NewMemLogger()
+		log := slog.New(NewSlogHandler(mem))
+		log.With("x", 1).Error("boom", "y", "z")
+		So(mem.ExtractMsgs(), ShouldResemble, []LogMessage{
+			{Loglevel: ErrorLevel, Msg: "boom", Fields: Fields{{"x", int64(1)}, {"y", "z"}}},
+		})
+
+		Convey("should work with a TeeLogger", func() {
+			other := NewMemLogger()
+			other.SetLogLevel(WarnLevel)
+			mem.SetLogLevel(ErrorLevel)
+			log := slog.New(NewSlogHandler(NewTeeLogger(mem, other)))
+			log.Info("dropped")
+			log.Warn("warn")
+			So(mem.ExtractMsgs(), ShouldBeEmpty)
+			So(other.ExtractMsgs(), ShouldResemble, []LogMessage{{Loglevel: WarnLevel, Msg: "warn"}})
+		})
+		Convey("should report the slog call point", func() {
+			var c captureWriter
+			log := slog.New(NewSlogHandler(&CancellableLogger{Logger: &StdLogger{Writer: &c, MinLevel: TraceLevel}}))
+			line := nextLine()
+			log.Info("hi")
+			So(c.File, ShouldEndWith, "slog_test.go")
+			So(c.Line, ShouldEqual, line)
+		})
+	})
+}
+
+func TestSlogLogger(t *testing.T) {
+	Convey("SlogLogger", t, func() {
+		var buf bytes.Buffer
+		h := slog.NewTextHandler(&buf, &slog.HandlerOptions{AddSource: true, Level: SlogLevel(TraceLevel)})
+		log := NewSlogLogger(h, "Flow=f1", DebugLevel)
+
+		Convey("should log records with the caller's source, context and fields", func() {
+			log.With("block", "addFoo").Infof("Creating %d blocks", 20)
+			s := buf.String()
+			So(s, ShouldContainSubstring, "level=INFO")
+			So(s, ShouldContainSubstring, "slog_test.go:")
+			So(s, ShouldContainSubstring, `msg="Creating 20 blocks"`)
+			So(s, ShouldContainSubstring, "block=addFoo")
+			So(s, ShouldContainSubstring, `context="Flow=f1"`)
+		})
+		Convey("should honour its level", func() {
+			log.Trace("dropped")
+			So(buf.String(), ShouldBeEmpty)
+			log.SetLogLevel(TraceLevel)
+			log.Trace("kept")
+			So(buf.String(), ShouldContainSubstring, "level=DEBUG-4")
+		})
+	})
+
+	Convey("Both adapters should land in the same TextWriter output", t, func() {
+		var buf bytes.Buffer
+		text := NewTextLogger(&buf, "Flow=f1", TraceLevel)
+		slog.New(NewSlogHandler(text)).Info("from slog")
+		NewSlogLogger(NewSlogHandler(text), "Block=addFoo", TraceLevel).Info("from logging")
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		So(lines, ShouldHaveLength, 2)
+		So(lines[0], ShouldContainSubstring, "slog_test.go:")
+		So(lines[0], ShouldEndWith, "(Flow=f1): from slog")
+		So(lines[1], ShouldContainSubstring, "slog_test.go:")
+		So(lines[1], ShouldEndWith, "(Block=addFoo): from logging")
+	})
+
+	Convey("SlogHandler should report errors from the Writer", t, func() {
+		h := NewSlogHandler(&StdLogger{Writer: failingWriter{}, MinLevel: TraceLevel})
+		So(h.Handle(context.Background(), slog.Record{Message: "x"}), ShouldNotBeNil)
+	})
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(e Entry) error { return errors.New("write failed") }
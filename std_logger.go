@@ -5,7 +5,6 @@ import (
 	"io"
 	"os"
 	"runtime"
-	"strings"
 	"sync/atomic"
 	"time"
 )
@@ -13,13 +12,14 @@ import (
 // NewTextLogger returns a Logger that saves all log output to the specified writer
 // as text.  Each line is logged with the following format:
 //
-//    LMMDD HH:MM:SSSZ filename.go:## (context): msg...
+//    LMMDD HH:MM:SSSZ filename.go:## (context) [key=value ...]: msg...
 //
 // where:
-//    L is the log level (D = debug, I = Info, E = Error)
+//    L is the log level (T = Trace, D = Debug, I = Info, W = Warn, E = Error, F = Fatal)
 //    MMDD HH:MM:SS.SSSZ = timestamp: Month/Day/Hour/Minute/Second/Timezone (Z = UTC)
 //    filename.go:## is the filename and line number where log was called from.
 //    context is the user-specified context string associated with the logger
+//    key=value pairs are the fields attached with With, omitted if there are none
 //
 // The context allows adding arbitrary additional context data to the log
 // entries, for example which block sourced a particular message.
@@ -30,7 +30,25 @@ func NewTextLogger(dest io.Writer, context string, minLevel Level) Logger {
 	if dest == nil {
 		dest = os.Stderr
 	}
-	return &StdLogger{context, &TextWriter{Writer: dest}, minLevel}
+	return &StdLogger{Context: context, Writer: &TextWriter{Writer: dest}, MinLevel: minLevel}
+}
+
+// NewJSONLogger returns a Logger that saves all log output to the specified
+// writer as JSON Lines, one object per entry.  See JSONWriter for the format.
+func NewJSONLogger(dest io.Writer, context string, minLevel Level) Logger {
+	if dest == nil {
+		dest = os.Stderr
+	}
+	return &StdLogger{Context: context, Writer: &JSONWriter{Writer: dest}, MinLevel: minLevel}
+}
+
+// NewLogfmtLogger returns a Logger that saves all log output to the specified
+// writer as logfmt, one line per entry.  See LogfmtWriter for the format.
+func NewLogfmtLogger(dest io.Writer, context string, minLevel Level) Logger {
+	if dest == nil {
+		dest = os.Stderr
+	}
+	return &StdLogger{Context: context, Writer: &LogfmtWriter{Writer: dest}, MinLevel: minLevel}
 }
 
 // StdLogger is a simple implementation that writes to the specified io.Writer.
@@ -40,36 +58,32 @@ type StdLogger struct {
 	Context  string
 	Writer   Writer
 	MinLevel Level
+	// Fields are structured key/value pairs included with all log messages.
+	Fields Fields
+	// VModule, if set, overrides MinLevel for messages logged from matching
+	// source files.
+	VModule *VModule
+	// NoCaller skips finding the file and line that logged each entry, which
+	// is the most expensive part of logging it.  Entries then have an empty
+	// File and a Line of -1.  With a VModule, entries whose level a pattern
+	// could change still have their file and line.
+	NoCaller bool
+	// CallerSkip is the number of frames above the code that called into this
+	// package to skip when finding the origin of entries.  See WithCallerSkip.
+	CallerSkip int
 }
 
+// Pos returns the file and line of the code that called into this package,
+// skipping Helper functions and CallerSkip further frames.
 func (s *StdLogger) Pos() (file string, line int) {
-	// 3 is the number of stack frames to skip:
-	//   0: origin (this func)
-	//   1: logf
-	//   2: Debug/Info/Error
-	//   3: The caller
-	numframes := 3
-	// In the case of the system logger, we have one more:
-	//   3: The global Debug/Info/Error calls in logger.go.
-	//   4: The caller
-	if s == System {
-		numframes = 4
-	}
-	_, file, line, ok := runtime.Caller(numframes)
-
-	// Our initial guess might still land us in the logging subsystem.  This
-	// can occur when StdLogger is wrapped in another logger (for example,
-	// CancellableLogger).  In this case, search up a few more frames.
-	for ok && strings.HasSuffix(file, "_logger.go") && numframes < 10 {
-		numframes++
-		_, file, line, ok = runtime.Caller(numframes)
-	}
-
-	if !ok {
-		return "???", -1
-	}
+	f := s.caller(1)
+	return f.File, f.Line
+}
 
-	return file, line
+// caller returns the origin of an entry.  skip is the number of frames above
+// caller, such as stdlogf and Info, that are known to be in this package.
+func (s *StdLogger) caller(skip int) callerFrame {
+	return findCaller(skip+1, s.CallerSkip)
 }
 
 // stdlogf logs a formatted message at the given level.
@@ -78,23 +92,51 @@ func (s *StdLogger) Pos() (file string, line int) {
 // with Logf if we pass go vet -printfuncs=logf:2 (i.e., to state that the 3rd
 // argument to logf is the format string).
 func (s *StdLogger) stdlogf(level Level, fmtstr string, vals ...interface{}) {
-	if level < s.LogLevel() {
+	// Check the level bounds first: finding the call site costs far more.
+	minLevel := s.LogLevel()
+	if level < minLevel && !s.VModule.mayEnable(level) {
 		return // skip it!
 	}
 
-	file, line := s.Pos()
+	origin := callerFrame{Frame: runtime.Frame{Line: -1}}
+	if s.VModule.dependsOnSite(level, minLevel) {
+		origin = s.caller(2)
+		if !s.VModule.enabled(origin.pc, origin.File, level, minLevel) {
+			return
+		}
+	} else if !s.NoCaller {
+		origin = s.caller(2)
+	}
 	entry := Entry{
-		Level:   level,
-		Time:    time.Now(),
-		File:    file,
-		Line:    line,
-		Context: s.Context,
-		Fmt:     fmtstr,
-		Args:    vals,
+		Level:    level,
+		Time:     time.Now(),
+		File:     origin.File,
+		Line:     origin.Line,
+		Function: origin.Function,
+		Context:  s.Context,
+		Fmt:      fmtstr,
+		Args:     resolveLazy(vals),
+		Fields:   s.Fields,
 	}
-	err := s.Writer.Write(entry)
+	s.write(entry)
+}
+
+// writeEntry logs an entry that was captured elsewhere, such as from the
+// standard log package, keeping its time and origin.  The logger's level,
+// context and fields are applied as for its own entries.
+func (s *StdLogger) writeEntry(e Entry) {
+	if e.Level < s.LogLevel() {
+		return
+	}
+	e.Context = s.Context
+	e.Fields = append(s.Fields[:len(s.Fields):len(s.Fields)], e.Fields...)
+	s.write(e)
+}
+
+func (s *StdLogger) write(e Entry) {
+	err := s.Writer.Write(e)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Log write failed: %v\nEntry: %#v", err, entry)
+		fmt.Fprintf(os.Stderr, "Log write failed: %v\nEntry: %#v", err, e)
 	}
 }
 
@@ -103,13 +145,55 @@ const kNO_FORMAT = ""
 func (l *StdLogger) Trace(vals ...interface{}) { l.stdlogf(TraceLevel, kNO_FORMAT, vals...) }
 func (l *StdLogger) Debug(vals ...interface{}) { l.stdlogf(DebugLevel, kNO_FORMAT, vals...) }
 func (l *StdLogger) Info(vals ...interface{})  { l.stdlogf(InfoLevel, kNO_FORMAT, vals...) }
+func (l *StdLogger) Warn(vals ...interface{})  { l.stdlogf(WarnLevel, kNO_FORMAT, vals...) }
 func (l *StdLogger) Error(vals ...interface{}) { l.stdlogf(ErrorLevel, kNO_FORMAT, vals...) }
 
 func (l *StdLogger) Tracef(fmt string, params ...interface{}) { l.stdlogf(TraceLevel, fmt, params...) }
 func (l *StdLogger) Debugf(fmt string, params ...interface{}) { l.stdlogf(DebugLevel, fmt, params...) }
 func (l *StdLogger) Infof(fmt string, params ...interface{})  { l.stdlogf(InfoLevel, fmt, params...) }
+func (l *StdLogger) Warnf(fmt string, params ...interface{})  { l.stdlogf(WarnLevel, fmt, params...) }
 func (l *StdLogger) Errorf(fmt string, params ...interface{}) { l.stdlogf(ErrorLevel, fmt, params...) }
 
+// fatalf logs at FatalLevel.  It backs the package-level Fatal functions; see
+// fatalLogger.
+func (l *StdLogger) fatalf(fmt string, params ...interface{}) { l.stdlogf(FatalLevel, fmt, params...) }
+
+// With returns a copy of the logger that additionally attaches the given
+// key/value pairs to every entry.  The copy starts at the current log level but
+// its level is independent of the original's afterwards.
+func (l *StdLogger) With(keyvals ...interface{}) Logger { return l.with(keyvals...) }
+
+func (l *StdLogger) with(keyvals ...interface{}) *StdLogger {
+	return &StdLogger{
+		Context:    l.Context,
+		Writer:     l.Writer,
+		MinLevel:   l.LogLevel(),
+		Fields:     l.Fields.With(keyvals...),
+		VModule:    l.VModule,
+		NoCaller:   l.NoCaller,
+		CallerSkip: l.CallerSkip,
+	}
+}
+
+// WithCallerSkip returns a copy of the logger that skips n more frames when
+// finding the origin of entries.
+func (l *StdLogger) WithCallerSkip(n int) Logger {
+	c := l.with()
+	c.CallerSkip += n
+	return c
+}
+
+// Enabled reports whether an entry at level would be logged from the calling
+// code, taking VModule into account.
+func (l *StdLogger) Enabled(level Level) bool {
+	minLevel := l.LogLevel()
+	if !l.VModule.dependsOnSite(level, minLevel) {
+		return level >= minLevel
+	}
+	origin := l.caller(1)
+	return l.VModule.enabled(origin.pc, origin.File, level, minLevel)
+}
+
 func (l *StdLogger) LogLevel() Level { return Level(atomic.LoadInt32((*int32)(&l.MinLevel))) }
 func (l *StdLogger) SetLogLevel(newLevel Level) {
 	atomic.StoreInt32((*int32)(&l.MinLevel), int32(newLevel))
@@ -2,6 +2,7 @@ package logging
 
 import (
 	"bytes"
+	"io/ioutil"
 	"testing"
 	"time"
 
@@ -17,7 +18,7 @@ func (c *captureWriter) Write(e Entry) error { *c = captureWriter(e); return nil
 func TestStdLogger(t *testing.T) {
 	Convey("Standard Logger", t, func() {
 		var c captureWriter
-		log := StdLogger{"test", &c, TraceLevel}
+		log := StdLogger{Context: "test", Writer: &c, MinLevel: TraceLevel}
 		Convey("should capture the logging time", func() {
 			t0 := time.Now()
 			log.Info("")
@@ -38,6 +39,8 @@ func TestStdLogger(t *testing.T) {
 			So(c.Level, ShouldEqual, DebugLevel)
 			log.Info("")
 			So(c.Level, ShouldEqual, InfoLevel)
+			log.Warn("")
+			So(c.Level, ShouldEqual, WarnLevel)
 			log.Error("")
 			So(c.Level, ShouldEqual, ErrorLevel)
 			log.Trace("")
@@ -79,6 +82,19 @@ func TestStdLogger(t *testing.T) {
 
 			So(log.LogLevel(), ShouldEqual, InfoLevel)
 		})
+		Convey("should attach fields from With", func() {
+			derived := log.With("flow", "f1", "block", "addFoo")
+			derived.Info("hi")
+			So(c.Fields, ShouldResemble, Fields{{"flow", "f1"}, {"block", "addFoo"}})
+			So(c.Context, ShouldEqual, "test")
+			So(c.File, ShouldContainSubstring, "std_logger_test.go")
+
+			derived.With("block", "mulBar").Info("hi")
+			So(c.Fields, ShouldResemble, Fields{{"flow", "f1"}, {"block", "mulBar"}})
+
+			log.Info("hi")
+			So(c.Fields, ShouldBeNil)
+		})
 
 	})
 }
@@ -111,7 +127,7 @@ func TestNewTextLogger(t *testing.T) {
 func TestSystemLogger(t *testing.T) {
 	var c captureWriter
 	var saved Logger
-	System, saved = &StdLogger{"fake system", &c, TraceLevel}, System
+	System, saved = &StdLogger{Context: "fake system", Writer: &c, MinLevel: TraceLevel}, System
 	defer func() { System = saved }()
 
 	Convey("The global system logger", t, func() {
@@ -132,3 +148,35 @@ func TestWrappedLoggerTracing(t *testing.T) {
 		So(s, ShouldContainSubstring, "std_logger_test.go")
 	})
 }
+
+func TestStdLoggerAllocs(t *testing.T) {
+	Convey("StdLogger should only allocate the argument slice", t, func() {
+		log := &StdLogger{Context: "Flow=f1", Writer: &TextWriter{Writer: ioutil.Discard}, MinLevel: InfoLevel}
+		So(testing.AllocsPerRun(100, func() { log.Info("Creating blocks") }), ShouldBeLessThanOrEqualTo, 1)
+		wrapped := NewTeeLogger(log)
+		So(testing.AllocsPerRun(100, func() { wrapped.Info("Creating blocks") }), ShouldBeLessThanOrEqualTo, 1)
+	})
+}
+
+func BenchmarkStdLogger(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		log  *StdLogger
+	}{
+		{"Enabled", &StdLogger{Context: "Flow=f1", Writer: &TextWriter{Writer: ioutil.Discard}, MinLevel: InfoLevel}},
+		{"NoCaller", &StdLogger{Context: "Flow=f1", Writer: &TextWriter{Writer: ioutil.Discard}, MinLevel: InfoLevel, NoCaller: true}},
+		{"Disabled", &StdLogger{Context: "Flow=f1", Writer: &TextWriter{Writer: ioutil.Discard}, MinLevel: WarnLevel}},
+		// No pattern could enable Info, so it is dropped without finding the
+		// call site.
+		{"DisabledVModule", &StdLogger{Context: "Flow=f1", Writer: &TextWriter{Writer: ioutil.Discard}, MinLevel: WarnLevel, VModule: ParseVModuleOrDie("chatty=error")}},
+		{"DisabledVModuleSite", &StdLogger{Context: "Flow=f1", Writer: &TextWriter{Writer: ioutil.Discard}, MinLevel: WarnLevel, VModule: ParseVModuleOrDie("chatty=trace")}},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				bm.log.Info("Creating blocks")
+			}
+		})
+	}
+}
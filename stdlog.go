@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// CaptureStdLog redirects the output of the standard library log package to
+// logger at the given level, so that messages from third-party code get the
+// same format, levels and filtering as our own.  The file and line reported for
+// each entry are those of the log.Print call.  Entries below the logger's level
+// are dropped.
+//
+// The returned function restores the log package's previous output, flags and
+// prefix.  Calls to CaptureStdLog should not overlap.
+func CaptureStdLog(logger Logger, level Level) (restore func()) {
+	out, flags, prefix := log.Writer(), log.Flags(), log.Prefix()
+	log.SetOutput(&stdLogWriter{logger: logger, level: level})
+	log.SetFlags(log.Llongfile)
+	log.SetPrefix("")
+	return func() {
+		log.SetOutput(out)
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+	}
+}
+
+// stdLogOrigin matches the "file:line: " header that log.Llongfile produces.
+var stdLogOrigin = regexp.MustCompile(`^(.*?):(\d+): `)
+
+// stdLogWriter is the io.Writer installed by CaptureStdLog.  The log package
+// calls Write once per message.
+type stdLogWriter struct {
+	logger Logger
+	level  Level
+}
+
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	msg := bytes.TrimSuffix(p, []byte("\n"))
+	file, line := "", -1
+	if m := stdLogOrigin.FindSubmatchIndex(msg); m != nil {
+		file = string(msg[m[2]:m[3]])
+		line, _ = strconv.Atoi(string(msg[m[4]:m[5]]))
+		msg = msg[m[1]:]
+	}
+
+	s, ok := w.logger.(*StdLogger)
+	if !ok {
+		// Other loggers find the origin themselves, skipping the log package.
+		logAtLevel(w.logger, w.level, string(msg))
+		return len(p), nil
+	}
+	s.writeEntry(Entry{
+		Level: w.level,
+		Time:  time.Now(),
+		File:  file,
+		Line:  line,
+		Fmt:   kNO_FORMAT,
+		Args:  []interface{}{string(msg)},
+	})
+	return len(p), nil
+}
@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCaptureStdLog(t *testing.T) {
+	Convey("CaptureStdLog", t, func() {
+		var before bytes.Buffer
+		saved := log.Writer()
+		log.SetOutput(&before)
+		defer log.SetOutput(saved)
+
+		Convey("should send log package output to a StdLogger", func() {
+			var c captureWriter
+			std := &StdLogger{Context: "Flow=f1", Writer: &c, MinLevel: InfoLevel, Fields: Fields{{"a", 1}}}
+			restore := CaptureStdLog(std, WarnLevel)
+			log.Printf("disk %s is %d%% full", "sda", 93)
+			restore()
+
+			So(before.String(), ShouldBeEmpty)
+			So(c.Level, ShouldEqual, WarnLevel)
+			So(c.File, ShouldEndWith, "stdlog_test.go")
+			So(c.Line, ShouldBeGreaterThan, 0)
+			So(c.Context, ShouldEqual, "Flow=f1")
+			So(c.Fields, ShouldResemble, Fields{{"a", 1}})
+			So(Entry(c).Message(), ShouldEqual, "disk sda is 93% full")
+		})
+		Convey("should honour the logger's level", func() {
+			var c captureWriter
+			restore := CaptureStdLog(&StdLogger{Writer: &c, MinLevel: InfoLevel}, DebugLevel)
+			log.Print("dropped")
+			restore()
+			So(c.Args, ShouldBeNil)
+		})
+		Convey("should log through other Loggers", func() {
+			mem := NewMemLogger()
+			restore := CaptureStdLog(mem, ErrorLevel)
+			log.Println("boom")
+			restore()
+			So(mem.ExtractMsgs(), ShouldResemble, []LogMessage{{Loglevel: ErrorLevel, Msg: "boom"}})
+		})
+		Convey("should report the log call point through other Loggers", func() {
+			var c captureWriter
+			restore := CaptureStdLog(NewTeeLogger(&StdLogger{Writer: &c, MinLevel: TraceLevel}), InfoLevel)
+			line := nextLine()
+			log.Print("hi")
+			restore()
+			So(c.File, ShouldEndWith, "stdlog_test.go")
+			So(c.Line, ShouldEqual, line)
+		})
+		Convey("should restore the previous output, flags and prefix", func() {
+			flags, prefix := log.Flags(), log.Prefix()
+			restore := CaptureStdLog(NewMemLogger(), InfoLevel)
+			restore()
+			So(log.Flags(), ShouldEqual, flags)
+			So(log.Prefix(), ShouldEqual, prefix)
+			log.Print("after")
+			So(before.String(), ShouldContainSubstring, "after")
+		})
+	})
+}
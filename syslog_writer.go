@@ -0,0 +1,375 @@
+package logging
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SyslogFormat selects the message format written by a SyslogWriter.
+type SyslogFormat int
+
+const (
+	// RFC5424 is the format of RFC 5424.  The entry's context, origin and
+	// fields are sent as structured data.
+	RFC5424 SyslogFormat = iota
+	// RFC3164 is the older BSD format that many daemons still expect.  The
+	// origin, context and fields are prefixed to the message as by TextWriter.
+	RFC3164
+)
+
+// SyslogFacility is a syslog facility code.
+type SyslogFacility int
+
+const (
+	SyslogUser   SyslogFacility = 1
+	SyslogDaemon SyslogFacility = 3
+	SyslogLocal0 SyslogFacility = 16
+	SyslogLocal1 SyslogFacility = 17
+	SyslogLocal2 SyslogFacility = 18
+	SyslogLocal3 SyslogFacility = 19
+	SyslogLocal4 SyslogFacility = 20
+	SyslogLocal5 SyslogFacility = 21
+	SyslogLocal6 SyslogFacility = 22
+	SyslogLocal7 SyslogFacility = 23
+)
+
+// DefaultSyslogSDID is the default SD-ID of the structured data element that
+// holds an entry's context, origin and fields in RFC 5424 messages.  32473 is
+// the private enterprise number reserved for documentation; deployments with
+// their own number should set SyslogWriter.SDID.
+const DefaultSyslogSDID = "logging@32473"
+
+// SyslogWriter is a Writer that sends entries to a syslog daemon.  For example:
+//
+//	w := &SyslogWriter{Network: "tcp", Addr: "loghost:514", Facility: SyslogLocal0}
+//	defer w.Close()
+//	logger := &StdLogger{Context: "app", Writer: w, MinLevel: InfoLevel}
+//
+// Network is "unixgram" or "udp", to send each entry as a datagram, or "unix"
+// or "tcp", to send entries over a stream with octet-counting framing as
+// described in RFC 6587.  Addr is the socket path or host:port.  If Network is
+// empty, entries go to the local daemon's datagram socket at Addr, or else at
+// the first of /dev/log, /var/run/syslog and /var/run/log that accepts them.
+//
+// Levels map to syslog severities as follows: Trace and Debug to debug, Info to
+// informational, Warn to warning, Error to err and Fatal to crit.
+//
+// The connection is made on first Write.  If sending fails, SyslogWriter
+// reconnects and tries once more before returning the error, so a restarted
+// daemon is picked up again.  A stream connection closed by the daemon may only
+// report an error on the second write after it, so one entry can be lost.
+//
+// The zero value of Facility is treated as SyslogUser, since the kernel
+// facility is reserved for the kernel.  Hostname and AppName default to the
+// machine's host name and the base name of the program.  Timeout bounds
+// connecting and each write; zero means 10 seconds.
+type SyslogWriter struct {
+	Network  string
+	Addr     string
+	Format   SyslogFormat
+	Facility SyslogFacility
+	Hostname string
+	AppName  string
+	SDID     string
+	Timeout  time.Duration
+
+	once     sync.Once
+	hostname string
+	appName  string
+	procID   string
+
+	mutex  sync.Mutex
+	conn   net.Conn
+	stream bool
+	closed bool
+}
+
+var errSyslogWriterClosed = errors.New("SyslogWriter is closed")
+
+// localSyslogPaths are the usual locations of the local syslog socket.
+var localSyslogPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// defaultNetworkTimeout bounds connecting and writing for the network Writers
+// when they have no Timeout of their own.
+const defaultNetworkTimeout = 10 * time.Second
+
+func (w *SyslogWriter) Write(e Entry) error {
+	w.once.Do(w.setDefaults)
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if w.Format == RFC3164 {
+		w.appendRFC3164(buf, e)
+	} else {
+		w.appendRFC5424(buf, e)
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.closed {
+		return errSyslogWriterClosed
+	}
+	err := w.send(*buf)
+	if err != nil {
+		// The daemon may have restarted.
+		w.disconnect()
+		err = w.send(*buf)
+		if err != nil {
+			w.disconnect()
+		}
+	}
+	return err
+}
+
+// Close closes the connection to the daemon.  Later writes fail.
+func (w *SyslogWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.closed = true
+	return w.disconnect()
+}
+
+func (w *SyslogWriter) setDefaults() {
+	w.hostname = w.Hostname
+	if w.hostname == "" {
+		w.hostname, _ = os.Hostname()
+	}
+	w.appName = w.AppName
+	if w.appName == "" {
+		w.appName = filepath.Base(os.Args[0])
+	}
+	w.procID = strconv.Itoa(os.Getpid())
+}
+
+func (w *SyslogWriter) timeout() time.Duration {
+	if w.Timeout > 0 {
+		return w.Timeout
+	}
+	return defaultNetworkTimeout
+}
+
+// send writes msg to the daemon, connecting first if necessary.  w.mutex must
+// be held.
+func (w *SyslogWriter) send(msg []byte) error {
+	if w.conn == nil {
+		if err := w.connect(); err != nil {
+			return err
+		}
+	}
+	if err := w.conn.SetWriteDeadline(time.Now().Add(w.timeout())); err != nil {
+		return err
+	}
+	if !w.stream {
+		_, err := w.conn.Write(msg)
+		return err
+	}
+	// Write the frame in one call, so that the length and message cannot be
+	// separated by a failure.
+	frame := getBuffer()
+	defer putBuffer(frame)
+	*frame = strconv.AppendInt(*frame, int64(len(msg)), 10)
+	*frame = append(*frame, ' ')
+	*frame = append(*frame, msg...)
+	_, err := w.conn.Write(*frame)
+	return err
+}
+
+// connect dials the daemon.  w.mutex must be held.
+func (w *SyslogWriter) connect() error {
+	if w.Network != "" {
+		conn, err := net.DialTimeout(w.Network, w.Addr, w.timeout())
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+		switch w.Network {
+		case "tcp", "tcp4", "tcp6", "unix":
+			w.stream = true
+		default:
+			w.stream = false
+		}
+		return nil
+	}
+
+	paths := localSyslogPaths
+	if w.Addr != "" {
+		paths = []string{w.Addr}
+	}
+	var err error
+	for _, path := range paths {
+		var conn net.Conn
+		if conn, err = net.DialTimeout("unixgram", path, w.timeout()); err == nil {
+			w.conn, w.stream = conn, false
+			return nil
+		}
+	}
+	return err
+}
+
+// disconnect closes the connection, if any.  w.mutex must be held.
+func (w *SyslogWriter) disconnect() error {
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// syslogSeverity returns the syslog severity of entries at level l.
+func syslogSeverity(l Level) int {
+	switch {
+	case l <= DebugLevel:
+		return 7
+	case l == InfoLevel:
+		return 6
+	case l == WarnLevel:
+		return 4
+	case l == ErrorLevel:
+		return 3
+	default:
+		return 2
+	}
+}
+
+func (w *SyslogWriter) appendPriority(b []byte, l Level) []byte {
+	facility := w.Facility
+	if facility == 0 {
+		facility = SyslogUser
+	}
+	b = append(b, '<')
+	b = strconv.AppendInt(b, int64(facility)*8+int64(syslogSeverity(l)), 10)
+	return append(b, '>')
+}
+
+// appendRFC5424 appends e to buf as an RFC 5424 message:
+//
+//	<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID - [SD-ID context=... origin=... fields...] MSG
+func (w *SyslogWriter) appendRFC5424(buf *buffer, e Entry) {
+	b := w.appendPriority(*buf, e.Level)
+	b = append(b, "1 "...)
+	if e.Time.IsZero() {
+		b = append(b, '-')
+	} else {
+		b = e.Time.AppendFormat(b, "2006-01-02T15:04:05.000000Z07:00")
+	}
+	b = append(b, ' ')
+	b = appendSyslogHeaderField(b, w.hostname, 255)
+	b = append(b, ' ')
+	b = appendSyslogHeaderField(b, w.appName, 48)
+	b = append(b, ' ')
+	b = appendSyslogHeaderField(b, w.procID, 128)
+	b = append(b, " - "...) // no MSGID
+
+	if e.Context == "" && e.File == "" && len(e.Fields) == 0 {
+		b = append(b, '-')
+	} else {
+		sdID := w.SDID
+		if sdID == "" {
+			sdID = DefaultSyslogSDID
+		}
+		b = append(b, '[')
+		b = append(b, sdID...)
+		if e.Context != "" {
+			b = appendSDParam(b, "context", e.Context)
+		}
+		if e.File != "" {
+			b = append(b, ` origin="`...)
+			b = appendSDValue(b, string(appendOrigin(nil, e.File, e.Line)))
+			b = append(b, '"')
+		}
+		for _, f := range e.Fields {
+			b = appendSDParam(b, f.Key, fieldText(f.Value))
+		}
+		b = append(b, ']')
+	}
+	b = append(b, ' ')
+	*buf = b
+	appendMessage(buf, e.Fmt, e.Args)
+}
+
+// appendRFC3164 appends e to buf as an RFC 3164 message:
+//
+//	<PRI>Mmm dd hh:mm:ss HOSTNAME APP-NAME[PROCID]: origin (context) [fields]: MSG
+func (w *SyslogWriter) appendRFC3164(buf *buffer, e Entry) {
+	b := w.appendPriority(*buf, e.Level)
+	b = e.Time.AppendFormat(b, time.Stamp)
+	b = append(b, ' ')
+	b = appendSyslogHeaderField(b, w.hostname, 255)
+	b = append(b, ' ')
+	b = appendSyslogHeaderField(b, w.appName, 32)
+	b = append(b, '[')
+	b = append(b, w.procID...)
+	b = append(b, "]: "...)
+	b = appendOrigin(b, e.File, e.Line)
+	b = append(b, " ("...)
+	b = appendTextContext(b, e.Context)
+	b = append(b, ')')
+	if len(e.Fields) > 0 {
+		b = append(b, " ["...)
+		b = e.Fields.appendTo(b)
+		b = append(b, ']')
+	}
+	b = append(b, ": "...)
+	*buf = b
+	appendMessage(buf, e.Fmt, e.Args)
+}
+
+// appendSyslogHeaderField appends s truncated to max bytes, with any characters
+// other than printable ASCII replaced by '_', or "-" if s is empty.
+func appendSyslogHeaderField(b []byte, s string, max int) []byte {
+	if s == "" {
+		return append(b, '-')
+	}
+	if len(s) > max {
+		s = s[:max]
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c > ' ' && c < 0x7f {
+			b = append(b, c)
+		} else {
+			b = append(b, '_')
+		}
+	}
+	return b
+}
+
+// appendSDParam appends a structured data parameter, name="value".  Characters
+// that are not allowed in SD-NAMEs are replaced by '_'.
+func appendSDParam(b []byte, name, value string) []byte {
+	b = append(b, ' ')
+	if name == "" {
+		name = "_"
+	}
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	for i := 0; i < len(name); i++ {
+		if c := name[i]; c > ' ' && c < 0x7f && c != '=' && c != ']' && c != '"' {
+			b = append(b, c)
+		} else {
+			b = append(b, '_')
+		}
+	}
+	b = append(b, `="`...)
+	b = appendSDValue(b, value)
+	return append(b, '"')
+}
+
+// appendSDValue appends a structured data parameter value, escaping '"', '\'
+// and ']' with a backslash.
+func appendSDValue(b []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\', ']':
+			b = append(b, '\\', c)
+		default:
+			b = append(b, c)
+		}
+	}
+	return b
+}
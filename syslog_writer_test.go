@@ -0,0 +1,149 @@
+package logging
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// readSyslogFrame reads one octet-counted syslog message from r.
+func readSyslogFrame(r *bufio.Reader) (string, error) {
+	length, err := r.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(length[:len(length)-1])
+	if err != nil {
+		return "", err
+	}
+	msg := make([]byte, n)
+	_, err = io.ReadFull(r, msg)
+	return string(msg), err
+}
+
+func TestSyslogWriter(t *testing.T) {
+	Convey("SyslogWriter", t, func() {
+		pid := os.Getpid()
+		entry := Entry{
+			Level:   WarnLevel,
+			Time:    time.Date(2016, 5, 23, 21, 21, 18, 901234000, time.UTC),
+			File:    "/src/flow/block.go",
+			Line:    12,
+			Context: "Flow=f1",
+			Fmt:     "Creating %d blocks",
+			Args:    []interface{}{20},
+			Fields:  Fields{{"block", "addFoo"}, {"odd key", `a "b"]`}},
+		}
+
+		dir, err := ioutil.TempDir("", "syslog_writer_test")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		Convey("should send RFC 5424 datagrams to a unix socket", func() {
+			path := filepath.Join(dir, "log")
+			conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+			So(err, ShouldBeNil)
+			defer conn.Close()
+
+			w := &SyslogWriter{Network: "unixgram", Addr: path, Facility: SyslogLocal0, Hostname: "host1", AppName: "app"}
+			defer w.Close()
+			So(w.Write(entry), ShouldBeNil)
+
+			buf := make([]byte, 1024)
+			n, err := conn.Read(buf)
+			So(err, ShouldBeNil)
+			So(string(buf[:n]), ShouldEqual, fmt.Sprintf(`<132>1 2016-05-23T21:21:18.901234Z host1 app %d - `+
+				`[logging@32473 context="Flow=f1" origin="block.go:12" block="addFoo" odd_key="a \"b\"\]"] Creating 20 blocks`, pid))
+
+			Convey("and use the local socket if no network is given", func() {
+				local := &SyslogWriter{Addr: path, Hostname: "host1", AppName: "app", SDID: "x@1"}
+				defer local.Close()
+				So(local.Write(Entry{Level: TraceLevel, Time: entry.Time, Line: -1, Args: []interface{}{"hi"}}), ShouldBeNil)
+				n, err := conn.Read(buf)
+				So(err, ShouldBeNil)
+				So(string(buf[:n]), ShouldEqual, fmt.Sprintf("<15>1 2016-05-23T21:21:18.901234Z host1 app %d - - hi", pid))
+			})
+		})
+
+		Convey("should send RFC 3164 datagrams over UDP", func() {
+			conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			So(err, ShouldBeNil)
+			defer conn.Close()
+
+			w := &SyslogWriter{Network: "udp", Addr: conn.LocalAddr().String(), Format: RFC3164, Hostname: "host1", AppName: "app"}
+			defer w.Close()
+			So(w.Write(entry), ShouldBeNil)
+
+			buf := make([]byte, 1024)
+			n, err := conn.Read(buf)
+			So(err, ShouldBeNil)
+			So(string(buf[:n]), ShouldEqual, fmt.Sprintf(`<12>May 23 21:21:18 host1 app[%d]: `+
+				`block.go:12 (Flow=f1) [block=addFoo "odd key"="a \"b\"]"]: Creating 20 blocks`, pid))
+		})
+
+		Convey("should frame entries over TCP and reconnect", func() {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			So(err, ShouldBeNil)
+			defer ln.Close()
+			conns := make(chan net.Conn, 2)
+			go func() {
+				for {
+					conn, err := ln.Accept()
+					if err != nil {
+						return
+					}
+					conns <- conn
+				}
+			}()
+
+			w := &SyslogWriter{Network: "tcp", Addr: ln.Addr().String(), Hostname: "host1", AppName: "app"}
+			defer w.Close()
+			levels := map[Level]string{TraceLevel: "<15>", DebugLevel: "<15>", InfoLevel: "<14>",
+				WarnLevel: "<12>", ErrorLevel: "<11>", FatalLevel: "<10>"}
+			for level := TraceLevel; level <= FatalLevel; level++ {
+				So(w.Write(Entry{Level: level, Time: entry.Time, Line: -1, Args: []interface{}{"multi\nline"}}), ShouldBeNil)
+			}
+
+			first := <-conns
+			r := bufio.NewReader(first)
+			for level := TraceLevel; level <= FatalLevel; level++ {
+				msg, err := readSyslogFrame(r)
+				So(err, ShouldBeNil)
+				So(msg, ShouldStartWith, levels[level]+"1 ")
+				So(msg, ShouldEndWith, " - multi\nline")
+			}
+
+			// Simulate a daemon restart.  The first write after it may be
+			// lost, but a later one must arrive on a new connection.
+			first.Close()
+			var second net.Conn
+			for i := 0; second == nil && i < 100; i++ {
+				w.Write(Entry{Level: InfoLevel, Time: entry.Time, Line: -1, Args: []interface{}{"again"}})
+				select {
+				case second = <-conns:
+				case <-time.After(10 * time.Millisecond):
+				}
+			}
+			So(second, ShouldNotBeNil)
+			defer second.Close()
+			msg, err := readSyslogFrame(bufio.NewReader(second))
+			So(err, ShouldBeNil)
+			So(msg, ShouldEndWith, " - again")
+		})
+
+		Convey("should fail once closed", func() {
+			w := &SyslogWriter{Network: "udp", Addr: "127.0.0.1:9"}
+			So(w.Close(), ShouldBeNil)
+			So(w.Write(entry), ShouldEqual, errSyslogWriterClosed)
+		})
+	})
+}
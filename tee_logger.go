@@ -12,45 +12,114 @@ func NewTeeLogger(loggers ...Logger) *TeeLogger {
 }
 
 func (l *TeeLogger) Trace(vals ...interface{}) {
-	for _, logger := range l.loggers {
-		logger.Trace(vals...)
+	if vals, ok := l.resolve(TraceLevel, vals); ok {
+		for _, logger := range l.loggers {
+			logger.Trace(vals...)
+		}
 	}
 }
 func (l *TeeLogger) Debug(vals ...interface{}) {
-	for _, logger := range l.loggers {
-		logger.Debug(vals...)
+	if vals, ok := l.resolve(DebugLevel, vals); ok {
+		for _, logger := range l.loggers {
+			logger.Debug(vals...)
+		}
 	}
 }
 func (l *TeeLogger) Info(vals ...interface{}) {
-	for _, logger := range l.loggers {
-		logger.Info(vals...)
+	if vals, ok := l.resolve(InfoLevel, vals); ok {
+		for _, logger := range l.loggers {
+			logger.Info(vals...)
+		}
+	}
+}
+func (l *TeeLogger) Warn(vals ...interface{}) {
+	if vals, ok := l.resolve(WarnLevel, vals); ok {
+		for _, logger := range l.loggers {
+			logger.Warn(vals...)
+		}
 	}
 }
 func (l *TeeLogger) Error(vals ...interface{}) {
-	for _, logger := range l.loggers {
-		logger.Error(vals...)
+	if vals, ok := l.resolve(ErrorLevel, vals); ok {
+		for _, logger := range l.loggers {
+			logger.Error(vals...)
+		}
 	}
 }
 
 func (l *TeeLogger) Tracef(fmt string, params ...interface{}) {
-	for _, logger := range l.loggers {
-		logger.Tracef(fmt, params...)
+	if params, ok := l.resolve(TraceLevel, params); ok {
+		for _, logger := range l.loggers {
+			logger.Tracef(fmt, params...)
+		}
 	}
 }
 func (l *TeeLogger) Debugf(fmt string, params ...interface{}) {
-	for _, logger := range l.loggers {
-		logger.Debugf(fmt, params...)
+	if params, ok := l.resolve(DebugLevel, params); ok {
+		for _, logger := range l.loggers {
+			logger.Debugf(fmt, params...)
+		}
 	}
 }
 func (l *TeeLogger) Infof(fmt string, params ...interface{}) {
-	for _, logger := range l.loggers {
-		logger.Infof(fmt, params...)
+	if params, ok := l.resolve(InfoLevel, params); ok {
+		for _, logger := range l.loggers {
+			logger.Infof(fmt, params...)
+		}
+	}
+}
+func (l *TeeLogger) Warnf(fmt string, params ...interface{}) {
+	if params, ok := l.resolve(WarnLevel, params); ok {
+		for _, logger := range l.loggers {
+			logger.Warnf(fmt, params...)
+		}
 	}
 }
 func (l *TeeLogger) Errorf(fmt string, params ...interface{}) {
+	if params, ok := l.resolve(ErrorLevel, params); ok {
+		for _, logger := range l.loggers {
+			logger.Errorf(fmt, params...)
+		}
+	}
+}
+func (l *TeeLogger) fatalf(fmt string, params ...interface{}) {
+	if params, ok := l.resolve(FatalLevel, params); ok {
+		for _, logger := range l.loggers {
+			logFatalf(logger, fmt, params...)
+		}
+	}
+}
+
+// LogLevel returns the lowest level of the loggers, below which none of them
+// logs, or FatalLevel if there are none.
+func (l *TeeLogger) LogLevel() Level {
+	level := FatalLevel
 	for _, logger := range l.loggers {
-		logger.Errorf(fmt, params...)
+		if ll := logger.LogLevel(); ll < level {
+			level = ll
+		}
+	}
+	return level
+}
+
+// Enabled reports whether any of the loggers would log an entry at level.
+func (l *TeeLogger) Enabled(level Level) bool {
+	for _, logger := range l.loggers {
+		if logger.Enabled(level) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns vals with Lazy arguments evaluated, so that they are
+// evaluated once rather than by each logger, and whether any logger would log
+// them at all.
+func (l *TeeLogger) resolve(level Level, vals []interface{}) ([]interface{}, bool) {
+	if !l.Enabled(level) {
+		return nil, false
 	}
+	return resolveLazy(vals), true
 }
 
 func (l *TeeLogger) SetLogLevel(newLevel Level) {
@@ -59,4 +128,20 @@ func (l *TeeLogger) SetLogLevel(newLevel Level) {
 	}
 }
 
+func (l *TeeLogger) With(keyvals ...interface{}) Logger {
+	loggers := make([]Logger, len(l.loggers))
+	for i, logger := range l.loggers {
+		loggers[i] = logger.With(keyvals...)
+	}
+	return NewTeeLogger(loggers...)
+}
+
+func (l *TeeLogger) WithCallerSkip(n int) Logger {
+	loggers := make([]Logger, len(l.loggers))
+	for i, logger := range l.loggers {
+		loggers[i] = WithCallerSkip(logger, n)
+	}
+	return NewTeeLogger(loggers...)
+}
+
 var _ Logger = &TeeLogger{}
@@ -43,11 +43,27 @@ func TestTeeLogger(t *testing.T) {
 			teelogger.Infof("Bad News Bears: %s", "Ruxbin")
 			compareBuffers(InfoLevel, "Bad News Bears: Ruxbin")
 		})
+		Convey("Writes warnings to both", func() {
+			teelogger.Warn("Bad News Bears")
+			compareBuffers(WarnLevel, "Bad News Bears")
+			teelogger.Warnf("Bad News Bears: %s", "Ruxbin")
+			compareBuffers(WarnLevel, "Bad News Bears: Ruxbin")
+		})
 		Convey("Writes errors to both", func() {
 			teelogger.Error("Bad News Bears")
 			compareBuffers(ErrorLevel, "Bad News Bears")
 			teelogger.Errorf("Bad News Bears: %s", "Ruxbin")
 			compareBuffers(ErrorLevel, "Bad News Bears: Ruxbin")
 		})
+		Convey("Propagates fields to both", func() {
+			teelogger.With("flow", "f1").Info("Bad News Bears")
+			compareBuffers(InfoLevel, "[flow=f1]: Bad News Bears")
+		})
+		Convey("Reports the lowest level of its loggers", func() {
+			logger1.SetLogLevel(ErrorLevel)
+			logger2.SetLogLevel(WarnLevel)
+			So(teelogger.LogLevel(), ShouldEqual, WarnLevel)
+			So(NewTeeLogger().LogLevel(), ShouldEqual, FatalLevel)
+		})
 	})
 }
@@ -5,52 +5,184 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
-
-	"github.com/fluxio/iohelpers/line"
 )
 
 // If long lines wrap multiple lines, use this prefix for each continuation line
 const continuation = "    "
 
+// textTimestampFormat is the time layout of TextWriter timestamps.
+const textTimestampFormat = "0102 15:04:05.000Z0700"
+
 type TextWriter struct {
 	Writer io.Writer
 	mutex  sync.Mutex
 }
 
-func (t *TextWriter) fmtTimestamp(ts time.Time) string { return ts.Format("0102 15:04:05.000Z0700") }
-func (t *TextWriter) fmtOrigin(file string, line int) string {
-	if file == "" {
-		file = "???"
-	} else {
-		file = filepath.Base(file)
-	}
-	if line == -1 {
-		return file
-	}
-	return fmt.Sprintf("%s:%d", file, line)
-}
-
 func (t *TextWriter) Write(e Entry) error {
 	// First we construct an in-memory string of the of entry.  This
 	// can be done in parallel for all calling threads.
-	var buf bytes.Buffer
-	w := line.PrefixWriter{&buf, []byte(continuation), true}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	b := *buf
+
 	// Prefix
-	fmt.Fprintf(&w, "%s%s %s (%s): ", e.Level, t.fmtTimestamp(e.Time),
-		t.fmtOrigin(e.File, e.Line), e.Context)
+	b = appendLevelLetter(b, e.Level)
+	b = appendTextTimestamp(b, e.Time)
+	b = append(b, ' ')
+	b = appendOrigin(b, e.File, e.Line)
+	b = append(b, " ("...)
+	b = appendTextContext(b, e.Context)
+	b = append(b, ')')
+	if len(e.Fields) > 0 {
+		b = append(b, " ["...)
+		b = e.Fields.appendTo(b)
+		b = append(b, ']')
+	}
+	b = append(b, ": "...)
 	// Content
-	if e.Fmt == kNO_FORMAT {
-		fmt.Fprintln(&w, e.Args...)
-	} else {
-		fmt.Fprintf(&w, e.Fmt+"\n", e.Args...)
+	*buf = b
+	appendMessage(buf, e.Fmt, e.Args)
+	*buf = append(*buf, '\n')
+	out := buf
+	if bytes.IndexByte((*buf)[:len(*buf)-1], '\n') >= 0 {
+		out = getBuffer()
+		defer putBuffer(out)
+		*out = indentContinuations(*out, *buf)
 	}
 
 	// Then we lock and write to the final output writer in one go.
 	t.mutex.Lock()
-	_, err := buf.WriteTo(t.Writer)
+	_, err := t.Writer.Write(*out)
 	t.mutex.Unlock()
 
 	return err
 }
+
+// buffer is a byte slice that can be used as an io.Writer, for fmt.
+type buffer []byte
+
+func (b *buffer) Write(p []byte) (int, error) {
+	*b = append(*b, p...)
+	return len(p), nil
+}
+
+func (b *buffer) WriteString(s string) (int, error) {
+	*b = append(*b, s...)
+	return len(s), nil
+}
+
+// maxPooledBuffer is the largest buffer kept for reuse, so that one huge entry
+// does not pin its memory for good.
+const maxPooledBuffer = 64 << 10
+
+var bufferPool = sync.Pool{New: func() interface{} { b := make(buffer, 0, 512); return &b }}
+
+func getBuffer() *buffer { return bufferPool.Get().(*buffer) }
+
+func putBuffer(b *buffer) {
+	if cap(*b) > maxPooledBuffer {
+		return
+	}
+	*b = (*b)[:0]
+	bufferPool.Put(b)
+}
+
+// appendMessage renders the message of an entry with the given format and
+// arguments, as Entry.Message does.
+func appendMessage(buf *buffer, fmtstr string, args []interface{}) {
+	if fmtstr != kNO_FORMAT {
+		fmt.Fprintf(buf, fmtstr, args...)
+		return
+	}
+	if len(args) == 1 {
+		if s, ok := args[0].(string); ok {
+			*buf = append(*buf, s...)
+			return
+		}
+	}
+	fmt.Fprintln(buf, args...)
+	*buf = (*buf)[:len(*buf)-1] // drop the newline
+}
+
+// indentContinuations appends src to dst, indenting every line after the first
+// by continuation.  src must end in a newline, which is left alone.
+func indentContinuations(dst, src buffer) buffer {
+	for {
+		i := bytes.IndexByte(src, '\n')
+		dst = append(dst, src[:i+1]...)
+		src = src[i+1:]
+		if len(src) == 0 {
+			return dst
+		}
+		dst = append(dst, continuation...)
+	}
+}
+
+func appendLevelLetter(b []byte, l Level) []byte {
+	if l < TraceLevel || l > FatalLevel {
+		return fmt.Appendf(b, "%s", l) // as formatted before; String panics
+	}
+	return append(b, l.String()...)
+}
+
+// appendTextTimestamp appends ts formatted with textTimestampFormat.  It is
+// equivalent to ts.AppendFormat(b, textTimestampFormat), but faster.
+func appendTextTimestamp(b []byte, ts time.Time) []byte {
+	_, month, day := ts.Date()
+	hour, min, sec := ts.Clock()
+	b = appendTwoDigits(b, int(month))
+	b = appendTwoDigits(b, day)
+	b = append(b, ' ')
+	b = appendTwoDigits(b, hour)
+	b = append(b, ':')
+	b = appendTwoDigits(b, min)
+	b = append(b, ':')
+	b = appendTwoDigits(b, sec)
+	b = append(b, '.')
+	ms := ts.Nanosecond() / int(time.Millisecond)
+	b = append(b, byte('0'+ms/100), byte('0'+ms/10%10), byte('0'+ms%10))
+
+	_, offset := ts.Zone()
+	if offset == 0 {
+		return append(b, 'Z')
+	}
+	sign := byte('+')
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	b = append(b, sign)
+	b = appendTwoDigits(b, offset/3600)
+	return appendTwoDigits(b, offset/60%60)
+}
+
+func appendTwoDigits(b []byte, n int) []byte {
+	return append(b, byte('0'+n/10%10), byte('0'+n%10))
+}
+
+// appendTextContext appends the context of an entry.  Contexts containing ')'
+// or a newline, or starting with a quote, are quoted, so that LogReader can
+// tell where they end.
+func appendTextContext(b []byte, context string) []byte {
+	if strings.ContainsAny(context, ")\n") || strings.HasPrefix(context, `"`) {
+		return strconv.AppendQuote(b, context)
+	}
+	return append(b, context...)
+}
+
+// appendOrigin appends the base name of file and the line, if known.
+func appendOrigin(b []byte, file string, line int) []byte {
+	if file == "" {
+		b = append(b, "???"...)
+	} else {
+		b = append(b, filepath.Base(file)...)
+	}
+	if line == -1 {
+		return b
+	}
+	b = append(b, ':')
+	return strconv.AppendInt(b, int64(line), 10)
+}
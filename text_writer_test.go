@@ -3,6 +3,7 @@ package logging
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"runtime"
 	"strings"
 	"sync"
@@ -39,13 +40,18 @@ func TestTextWriter(t *testing.T) {
 	Convey("TextWriter", t, func() {
 		buf.Reset()
 		Convey("should format entries with no format arg correctly", func() {
-			w.Write(Entry{InfoLevel, ts, "/path/to/file.js", 12, "ctx", kNO_FORMAT, args("Hi", "there")})
+			w.Write(Entry{Level: InfoLevel, Time: ts, File: "/path/to/file.js", Line: 12, Context: "ctx", Fmt: kNO_FORMAT, Args: args("Hi", "there")})
 			So(buf.String(), ShouldEqual, "I0523 21:21:18.901Z file.js:12 (ctx): Hi there\n")
 		})
 		Convey("should format entries with a format arg correctly", func() {
-			w.Write(Entry{InfoLevel, ts, "/path/to/file.js", 12, "ctx", "(%s %d %s)", args("Hi", 4, "there")})
+			w.Write(Entry{Level: InfoLevel, Time: ts, File: "/path/to/file.js", Line: 12, Context: "ctx", Fmt: "(%s %d %s)", Args: args("Hi", 4, "there")})
 			So(buf.String(), ShouldEqual, "I0523 21:21:18.901Z file.js:12 (ctx): (Hi 4 there)\n")
 		})
+		Convey("should format fields after the context", func() {
+			w.Write(Entry{Level: InfoLevel, Time: ts, File: "/path/to/file.js", Line: 12, Context: "ctx",
+				Fmt: "hi", Fields: Fields{{"flow", "f1"}, {"msg", "two words"}}})
+			So(buf.String(), ShouldEqual, `I0523 21:21:18.901Z file.js:12 (ctx) [flow=f1 msg="two words"]: hi`+"\n")
+		})
 		Convey("should format the log level correctly", func() {
 			w.Write(Entry{Level: DebugLevel})
 			So(buf.String(), ShouldStartWith, "D")
@@ -53,8 +59,14 @@ func TestTextWriter(t *testing.T) {
 			w.Write(Entry{Level: InfoLevel})
 			So(buf.String(), ShouldStartWith, "I")
 			buf.Reset()
+			w.Write(Entry{Level: WarnLevel})
+			So(buf.String(), ShouldStartWith, "W")
+			buf.Reset()
 			w.Write(Entry{Level: ErrorLevel})
 			So(buf.String(), ShouldStartWith, "E")
+			buf.Reset()
+			w.Write(Entry{Level: FatalLevel})
+			So(buf.String(), ShouldStartWith, "F")
 		})
 		Convey("should format entries without line numbers correctly", func() {
 			w.Write(Entry{File: "/path/to/file.js", Line: -1})
@@ -68,9 +80,10 @@ func TestTextWriter(t *testing.T) {
 			w.Write(Entry{Fmt: "a\nb\nc"})
 			So(buf.String(), ShouldContainSubstring, "a\n"+continuation+"b\n"+continuation+"c\n")
 		})
-		Convey("should format entries whose context spans multiple lines correctly", func() {
+		Convey("should quote contexts that span multiple lines", func() {
 			w.Write(Entry{Context: "a\nb\nc"})
-			So(buf.String(), ShouldContainSubstring, "(a\n"+continuation+"b\n"+continuation+"c)")
+			So(buf.String(), ShouldContainSubstring, `("a\nb\nc")`)
+			So(strings.Count(buf.String(), "\n"), ShouldEqual, 1)
 		})
 		Convey("should write messages atomically when written from many threads", func() {
 			runtime.GOMAXPROCS(10)
@@ -89,3 +102,51 @@ func TestTextWriter(t *testing.T) {
 		})
 	})
 }
+
+func TestTextTimestamp(t *testing.T) {
+	Convey("Text timestamps should match textTimestampFormat", t, func() {
+		zones := []*time.Location{
+			time.UTC,
+			time.FixedZone("PST", -8*3600),
+			time.FixedZone("IST", 5*3600+30*60),
+			time.FixedZone("GMT", 0),
+		}
+		ts := time.Date(2016, 12, 31, 23, 59, 59, 999999999, time.UTC)
+		for _, zone := range zones {
+			for _, d := range []time.Duration{0, 1, time.Millisecond, 37 * time.Hour, -200 * 24 * time.Hour} {
+				t := ts.Add(d).In(zone)
+				So(string(appendTextTimestamp(nil, t)), ShouldEqual, t.Format(textTimestampFormat))
+			}
+		}
+		So(string(appendTextTimestamp(nil, time.Time{})), ShouldEqual, time.Time{}.Format(textTimestampFormat))
+	})
+}
+
+func TestTextWriterAllocs(t *testing.T) {
+	Convey("TextWriter should not allocate for simple entries", t, func() {
+		w := &TextWriter{Writer: ioutil.Discard}
+		e := Entry{Level: InfoLevel, Time: time.Now(), File: "/path/to/file.go", Line: 12, Context: "Flow=f1",
+			Args: args("Creating blocks"), Fields: Fields{{"block", "addFoo"}, {"n", 20}}}
+		So(testing.AllocsPerRun(100, func() { w.Write(e) }), ShouldEqual, 0)
+	})
+}
+
+func BenchmarkTextWriter(b *testing.B) {
+	w := &TextWriter{Writer: ioutil.Discard}
+	e := Entry{Level: InfoLevel, Time: time.Now(), File: "/path/to/file.go", Line: 12, Context: "Flow=f1",
+		Fmt: "Creating %d blocks", Args: args(20), Fields: Fields{{"block", "addFoo"}}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w.Write(e)
+	}
+}
+
+func BenchmarkTextWriterMultiline(b *testing.B) {
+	w := &TextWriter{Writer: ioutil.Discard}
+	e := Entry{Level: InfoLevel, Time: time.Now(), File: "/path/to/file.go", Line: 12, Context: "Flow=f1",
+		Args: args("first line\nsecond line\nthird line")}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w.Write(e)
+	}
+}
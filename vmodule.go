@@ -0,0 +1,183 @@
+package logging
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// VModule overrides a StdLogger's level for the files that match a set of
+// patterns, in the style of glog's -vmodule flag.  A spec is a comma-separated
+// list of pattern=level pairs, for example:
+//
+//	readers*=trace,flow/*=debug,chatty=error
+//
+// Patterns are matched with path.Match against the caller's file name without
+// its ".go" suffix.  A pattern without a slash is matched against the base
+// name; one with N slashes is matched against the last N+1 path elements, so
+// "flow/*" matches any file directly in a directory named flow.  The first
+// matching pattern wins.  Files that match nothing use the logger's MinLevel.
+//
+// Decisions are cached per call site, so once a site has been seen the cost of
+// an override is a map lookup, plus finding the call site.  That is needed even
+// to drop an entry, unless its level is below both MinLevel and every pattern's
+// level; entries at levels that no pattern could change cost no more than
+// without a VModule.  VModule implements flag.Value and is safe for
+// concurrent use; Set replaces all patterns and clears the cache.
+type VModule struct {
+	config atomic.Value // *vmoduleConfig
+}
+
+type vmoduleConfig struct {
+	spec  string
+	rules []vmoduleRule
+	// minLevel and maxLevel are the lowest and highest levels of any rule.
+	// Entries below both minLevel and the logger's level can be dropped, and
+	// those at or above both maxLevel and the logger's level logged, without
+	// looking at the caller.
+	minLevel, maxLevel Level
+	// sites caches the level for each call site pc, or 0 if nothing matched.
+	sites sync.Map
+}
+
+type vmoduleRule struct {
+	pattern string
+	depth   int // number of path elements the pattern spans
+	level   Level
+}
+
+// ParseVModule parses a vmodule spec as described on VModule.
+func ParseVModule(spec string) (*VModule, error) {
+	var v VModule
+	if err := v.Set(spec); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ParseVModuleOrDie is like ParseVModule but panics if spec is invalid.
+func ParseVModuleOrDie(spec string) *VModule {
+	v, err := ParseVModule(spec)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Set replaces the patterns with those in spec.
+func (v *VModule) Set(spec string) error {
+	c := &vmoduleConfig{spec: spec, minLevel: FatalLevel + 1}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		eq := strings.LastIndex(item, "=")
+		if eq <= 0 {
+			return fmt.Errorf("Invalid vmodule item %q: want pattern=level", item)
+		}
+		pattern := strings.TrimSuffix(item[:eq], ".go")
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("Invalid vmodule pattern %q: %v", pattern, err)
+		}
+		level, err := ParseLevel(item[eq+1:])
+		if err != nil {
+			return fmt.Errorf("Invalid vmodule item %q: %v", item, err)
+		}
+		c.rules = append(c.rules, vmoduleRule{
+			pattern: pattern,
+			depth:   strings.Count(pattern, "/") + 1,
+			level:   level,
+		})
+		if level < c.minLevel {
+			c.minLevel = level
+		}
+		if level > c.maxLevel {
+			c.maxLevel = level
+		}
+	}
+	v.config.Store(c)
+	return nil
+}
+
+func (v *VModule) String() string {
+	if v == nil {
+		return ""
+	}
+	if c := v.load(); c != nil {
+		return c.spec
+	}
+	return ""
+}
+
+func (v *VModule) load() *vmoduleConfig {
+	c, _ := v.config.Load().(*vmoduleConfig)
+	return c
+}
+
+// mayEnable reports whether some pattern could enable an entry at level.
+func (v *VModule) mayEnable(level Level) bool {
+	if v == nil {
+		return false
+	}
+	c := v.load()
+	return c != nil && level >= c.minLevel
+}
+
+// dependsOnSite reports whether a pattern could change whether an entry at level
+// is logged by a logger whose own minimum level is minLevel.
+func (v *VModule) dependsOnSite(level, minLevel Level) bool {
+	if v == nil {
+		return false
+	}
+	c := v.load()
+	if c == nil {
+		return false
+	}
+	if level < minLevel {
+		return level >= c.minLevel
+	}
+	return level < c.maxLevel
+}
+
+// enabled reports whether an entry at level from the call site pc in file
+// should be logged, given the logger's own minimum level.
+func (v *VModule) enabled(pc uintptr, file string, level, minLevel Level) bool {
+	c := v.load()
+	if c == nil || len(c.rules) == 0 {
+		return level >= minLevel
+	}
+	site, ok := c.sites.Load(pc)
+	if !ok {
+		site, _ = c.sites.LoadOrStore(pc, c.match(file))
+	}
+	if override := site.(Level); override != 0 {
+		minLevel = override
+	}
+	return level >= minLevel
+}
+
+// match returns the level of the first rule matching file, or 0 if none does.
+func (c *vmoduleConfig) match(file string) Level {
+	file = strings.TrimSuffix(file, ".go")
+	for _, r := range c.rules {
+		if ok, _ := path.Match(r.pattern, lastElems(file, r.depth)); ok {
+			return r.level
+		}
+	}
+	return 0
+}
+
+// lastElems returns the last n slash-separated elements of p.
+func lastElems(p string, n int) string {
+	i := len(p)
+	for ; n > 0 && i > 0; n-- {
+		i = strings.LastIndex(p[:i], "/")
+		if i < 0 {
+			return p
+		}
+	}
+	return p[i+1:]
+}
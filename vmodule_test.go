@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"flag"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseVModule(t *testing.T) {
+	Convey("ParseVModule", t, func() {
+		Convey("should parse patterns and levels", func() {
+			v, err := ParseVModule("readers*=trace, flow/*=debug,chatty.go=error,")
+			So(err, ShouldBeNil)
+			c := v.load()
+			So(c.rules, ShouldResemble, []vmoduleRule{
+				{pattern: "readers*", depth: 1, level: TraceLevel},
+				{pattern: "flow/*", depth: 2, level: DebugLevel},
+				{pattern: "chatty", depth: 1, level: ErrorLevel},
+			})
+			So(c.minLevel, ShouldEqual, TraceLevel)
+			So(c.maxLevel, ShouldEqual, ErrorLevel)
+		})
+		Convey("should reject bad items", func() {
+			for _, spec := range []string{"readers", "=trace", "readers=loud", "[=info"} {
+				_, err := ParseVModule(spec)
+				So(err, ShouldNotBeNil)
+			}
+		})
+		Convey("should be usable as a flag", func() {
+			var v VModule
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.Var(&v, "vmodule", "")
+			So(fs.Parse([]string{"-vmodule=foo=debug"}), ShouldBeNil)
+			So(v.String(), ShouldEqual, "foo=debug")
+		})
+	})
+}
+
+func TestVModuleMatch(t *testing.T) {
+	Convey("VModule patterns", t, func() {
+		c := ParseVModuleOrDie("readers*=trace,flow/*=debug,*/fluxio/x/*=warn").load()
+		So(c.match("/src/logging/readers.go"), ShouldEqual, TraceLevel)
+		So(c.match("/src/logging/readers_test.go"), ShouldEqual, TraceLevel)
+		So(c.match("/src/flow/block.go"), ShouldEqual, DebugLevel)
+		So(c.match("/src/flow/sub/block.go"), ShouldEqual, 0)
+		So(c.match("/src/github.com/fluxio/x/y.go"), ShouldEqual, WarnLevel)
+		So(c.match("/src/logging/writers.go"), ShouldEqual, 0)
+		So(c.match("readers.go"), ShouldEqual, TraceLevel)
+	})
+}
+
+func TestStdLoggerVModule(t *testing.T) {
+	Convey("A StdLogger with a VModule", t, func() {
+		var c captureWriter
+		log := &StdLogger{Writer: &c, MinLevel: InfoLevel}
+
+		Convey("should lower the level for matching files", func() {
+			log.VModule = ParseVModuleOrDie("vmodule_test=trace")
+			log.Trace("verbose")
+			So(Entry(c).Message(), ShouldEqual, "verbose")
+			So(c.File, ShouldEndWith, "vmodule_test.go")
+		})
+		Convey("should raise the level for matching files", func() {
+			log.VModule = ParseVModuleOrDie("vmodule_*=error")
+			log.Warn("dropped")
+			So(c.Args, ShouldBeNil)
+			log.Error("kept")
+			So(Entry(c).Message(), ShouldEqual, "kept")
+		})
+		Convey("should use MinLevel for other files", func() {
+			log.VModule = ParseVModuleOrDie("readers=trace")
+			log.Debug("dropped")
+			So(c.Args, ShouldBeNil)
+			log.Info("kept")
+			So(Entry(c).Message(), ShouldEqual, "kept")
+		})
+		Convey("should only look for the caller when a pattern could apply", func() {
+			log.NoCaller = true
+			log.VModule = ParseVModuleOrDie("vmodule_test=debug")
+			log.Info("info")
+			So(c.Line, ShouldEqual, -1)
+			log.Debug("debug")
+			So(c.File, ShouldEndWith, "vmodule_test.go")
+			So(log.Enabled(TraceLevel), ShouldBeFalse)
+			So(log.Enabled(DebugLevel), ShouldBeTrue)
+			So(log.Enabled(InfoLevel), ShouldBeTrue)
+		})
+		Convey("should be shared by derived loggers and re-evaluated on Set", func() {
+			log.VModule = ParseVModuleOrDie("vmodule_test=trace")
+			derived := log.With("k", "v")
+			logAt := func() { derived.Debug("debug") }
+			logAt()
+			So(Entry(c).Message(), ShouldEqual, "debug")
+			c = captureWriter{}
+			So(log.VModule.Set("vmodule_test=info"), ShouldBeNil)
+			logAt()
+			So(c.Args, ShouldBeNil)
+		})
+	})
+}